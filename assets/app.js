@@ -0,0 +1,239 @@
+"use strict";
+
+const $ = (sel) => document.querySelector(sel);
+
+const state = {
+  rows: [],
+  context: [],
+  sortKey: "name",
+  sortDir: 1,
+  selected: null,
+};
+
+function option(select, value, text) {
+  const o = document.createElement("option");
+  o.value = value;
+  o.textContent = text;
+  select.appendChild(o);
+}
+
+function fmt(v, unit) {
+  return v.toFixed(2) + unit;
+}
+
+function showError(msg) {
+  const e = $("#error");
+  e.textContent = msg;
+  e.hidden = !msg;
+}
+
+async function fetchJSON(url) {
+  const resp = await fetch(url);
+  if (!resp.ok) {
+    throw new Error(await resp.text());
+  }
+  return resp.json();
+}
+
+async function init() {
+  const data = await fetchJSON("api/results");
+  data.results.forEach((label, i) => {
+    option($("#old"), i, label);
+    option($("#new"), i, label);
+  });
+  $("#new").value = data.results.length - 1;
+  data.metrics.forEach((m) => option($("#metric"), m, m));
+
+  for (const id of ["#old", "#new", "#metric"]) {
+    $(id).addEventListener("change", load);
+  }
+  $("#filter").addEventListener("input", render);
+  $("#significant").addEventListener("change", render);
+  $("#allctx").addEventListener("change", renderContext);
+  document.querySelectorAll("#rows th").forEach((th) => {
+    th.addEventListener("click", () => {
+      if (state.sortKey === th.dataset.key) {
+        state.sortDir = -state.sortDir;
+      } else {
+        state.sortKey = th.dataset.key;
+        state.sortDir = 1;
+      }
+      render();
+    });
+  });
+
+  await load();
+}
+
+async function load() {
+  const q = new URLSearchParams({
+    old: $("#old").value,
+    new: $("#new").value,
+    metric: $("#metric").value,
+  });
+  try {
+    const data = await fetchJSON("api/compare?" + q);
+    state.rows = data.rows;
+    state.context = data.context;
+    showError("");
+  } catch (err) {
+    state.rows = [];
+    state.context = [];
+    showError(err.message);
+  }
+  state.selected = null;
+  $("#plot").hidden = true;
+  render();
+  renderContext();
+}
+
+function sortValue(r, key) {
+  switch (key) {
+    case "delta":
+      return r.significant ? r.delta : 0;
+    case "p":
+      return r.p;
+    case "n":
+      return r.old.values.length + r.new.values.length;
+    case "old":
+      return r.old.mean;
+    case "new":
+      return r.new.mean;
+  }
+  return r.name;
+}
+
+function visibleRows() {
+  let re = null;
+  try {
+    re = new RegExp($("#filter").value);
+  } catch (err) {
+    re = null;
+  }
+  const onlySignificant = $("#significant").checked;
+  const rows = state.rows.filter((r) =>
+    (!re || re.test(r.name)) && (!onlySignificant || r.significant));
+  if (state.sortKey !== "name") {
+    rows.sort((a, b) => {
+      const va = sortValue(a, state.sortKey);
+      const vb = sortValue(b, state.sortKey);
+      return (va < vb ? -1 : va > vb ? 1 : 0) * state.sortDir;
+    });
+  } else if (state.sortDir < 0) {
+    rows.reverse();
+  }
+  return rows;
+}
+
+function cell(tr, text, cls) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (cls) {
+    td.className = cls;
+  }
+  tr.appendChild(td);
+  return td;
+}
+
+function render() {
+  document.querySelectorAll("#rows th").forEach((th) => {
+    th.className = th.dataset.key === state.sortKey ? (state.sortDir > 0 ? "asc" : "desc") : "";
+  });
+
+  const isTime = ["real", "cpu"].includes($("#metric").value);
+  const tbody = $("#rows tbody");
+  tbody.innerHTML = "";
+  for (const r of visibleRows()) {
+    const tr = document.createElement("tr");
+    if (r === state.selected) {
+      tr.className = "selected";
+    }
+    cell(tr, r.name);
+    let cls = "num";
+    if (r.significant && isTime) {
+      cls += r.delta > 0 ? " worse" : " better";
+    }
+    cell(tr, r.significant ? (r.delta >= 0 ? "+" : "") + r.delta.toFixed(2) + "%" : "~", cls);
+    cell(tr, r.p >= 0 ? r.p.toFixed(2) : r.note, "num");
+    cell(tr, r.old.values.length + "+" + r.new.values.length, "num");
+    cell(tr, fmt(r.old.mean, r.unit), "num");
+    cell(tr, fmt(r.new.mean, r.unit), "num");
+    tr.addEventListener("click", () => {
+      state.selected = r;
+      render();
+      plot(r);
+    });
+    tbody.appendChild(tr);
+  }
+}
+
+function renderContext() {
+  const all = $("#allctx").checked;
+  const tbody = $("#context tbody");
+  tbody.innerHTML = "";
+  for (const d of state.context) {
+    const differs = d.old !== d.new;
+    if (!all && (!differs || d.level === "ignore")) {
+      continue;
+    }
+    const tr = document.createElement("tr");
+    if (differs) {
+      tr.className = "differs";
+    }
+    cell(tr, d.field);
+    cell(tr, differs ? d.level : "=");
+    cell(tr, d.old || "-");
+    cell(tr, d.new || "-");
+    tbody.appendChild(tr);
+  }
+}
+
+const svgNS = "http://www.w3.org/2000/svg";
+
+function svgEl(parent, name, attrs) {
+  const e = document.createElementNS(svgNS, name);
+  for (const k in attrs) {
+    e.setAttribute(k, attrs[k]);
+  }
+  parent.appendChild(e);
+  return e;
+}
+
+// plot draws the repetitions of old and new on the same axis, one line
+// each, with the outliers hollow and the means as vertical bars.
+function plot(r) {
+  const section = $("#plot");
+  section.hidden = false;
+  section.querySelector("h2").textContent = r.name;
+
+  const svg = section.querySelector("svg");
+  svg.innerHTML = "";
+  const width = 800, left = 50, right = 20;
+  const all = [].concat(r.old.values, r.old.outliers || [], r.new.values, r.new.outliers || []);
+  let lo = Math.min(...all), hi = Math.max(...all);
+  if (lo === hi) {
+    lo -= 1;
+    hi += 1;
+  }
+  const x = (v) => left + (v - lo) / (hi - lo) * (width - left - right);
+
+  const lines = [["old", r.old, 35], ["new", r.new, 85]];
+  for (const [name, s, y] of lines) {
+    svgEl(svg, "text", {x: 5, y: y + 4}).textContent = name;
+    svgEl(svg, "line", {x1: left, x2: width - right, y1: y, y2: y, stroke: "#ddd"});
+    s.values.forEach((v, i) => {
+      svgEl(svg, "circle", {cx: x(v), cy: y + (i % 5 - 2) * 3, r: 3, class: name, fill: "currentColor", "fill-opacity": 0.6});
+    });
+    (s.outliers || []).forEach((v) => {
+      svgEl(svg, "circle", {cx: x(v), cy: y, r: 4, fill: "none", stroke: "#888"});
+    });
+    svgEl(svg, "line", {x1: x(s.mean), x2: x(s.mean), y1: y - 15, y2: y + 15, class: name, stroke: "currentColor", "stroke-width": 2});
+  }
+
+  for (let i = 0; i <= 4; i++) {
+    const v = lo + (hi - lo) * i / 4;
+    svgEl(svg, "text", {x: x(v), y: 130, "text-anchor": "middle"}).textContent = fmt(v, r.unit);
+  }
+}
+
+init().catch((err) => showError(err.message));
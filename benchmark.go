@@ -1,6 +1,6 @@
 package main
 
-import "fmt"
+import "encoding/json"
 
 type Result struct {
 	Benchmarks []Benchmark `json:"benchmarks"`
@@ -8,32 +8,59 @@ type Result struct {
 }
 
 type Context struct {
-	Date              string  `json:"date"`
-	Hostname          string  `json:"host_name"`
-	Executable        string  `json:"executable"`
-	Caches            []Cache `json:"caches"`
-	NumCPUs           int     `json:"num_cpus"`
-	MHzPerCPU         int     `json:"mhz_per_cpu"`
-	CPUScalingEnabled bool    `json:"cpu_scaling_enabled"`
+	// Custom has the key/values given with --benchmark_context.
+	Custom            map[string]string `json:"-"`
+	Date              string            `json:"date"`
+	Hostname          string            `json:"host_name"`
+	Executable        string            `json:"executable"`
+	LibraryBuildType  string            `json:"library_build_type"`
+	LibraryVersion    string            `json:"library_version"`
+	Caches            []Cache           `json:"caches"`
+	LoadAvg           []float64         `json:"load_avg"`
+	NumCPUs           int               `json:"num_cpus"`
+	MHzPerCPU         int               `json:"mhz_per_cpu"`
+	JSONSchemaVersion int               `json:"json_schema_version"`
+	CPUScalingEnabled bool              `json:"cpu_scaling_enabled"`
 }
 
-func (c Context) Equals(other Context) error {
-	if len(c.Caches) != len(other.Caches) {
-		return fmt.Errorf("different number of CPU caches: %d vs %d", len(c.Caches), len(other.Caches))
-	}
-	for i := range c.Caches {
-		if err := c.Caches[i].Equals(other.Caches[i]); err != nil {
-			return err
-		}
-	}
-	if c.NumCPUs != other.NumCPUs {
-		return fmt.Errorf("different number of CPUs: %d vs %d", c.NumCPUs, other.NumCPUs)
+// contextKeys are the keys of the context written by google benchmark,
+// the other string values are custom context.
+var contextKeys = map[string]bool{
+	"date":                true,
+	"host_name":           true,
+	"executable":          true,
+	"num_cpus":            true,
+	"mhz_per_cpu":         true,
+	"cpu_scaling_enabled": true,
+	"caches":              true,
+	"load_avg":            true,
+	"library_build_type":  true,
+	"library_version":     true,
+	"json_schema_version": true,
+}
+
+func (c *Context) UnmarshalJSON(data []byte) error {
+	type context Context
+	if err := json.Unmarshal(data, (*context)(c)); err != nil {
+		return err
 	}
-	if c.MHzPerCPU != other.MHzPerCPU {
-		return fmt.Errorf("different MHz/CPU: %d vs %d", c.MHzPerCPU, other.MHzPerCPU)
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
 	}
-	if c.CPUScalingEnabled != other.CPUScalingEnabled {
-		return fmt.Errorf("different CPU scaling: %v vs %v", c.CPUScalingEnabled, other.CPUScalingEnabled)
+	for k, v := range all {
+		if contextKeys[k] {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			continue
+		}
+		if c.Custom == nil {
+			c.Custom = make(map[string]string)
+		}
+		c.Custom[k] = s
 	}
 	return nil
 }
@@ -45,28 +72,97 @@ type Cache struct {
 	NumSharing int    `json:"num_sharing"`
 }
 
-func (c Cache) Equals(other Cache) error {
-	if c.Type != other.Type {
-		return fmt.Errorf("different type of CPU cache: %v vs %v", c.Type, other.Type)
-	}
-	if c.Level != other.Level {
-		return fmt.Errorf("different CPU cache level: %d vs %d", c.Level, other.Level)
-	}
-	if c.Size != other.Size {
-		return fmt.Errorf("different CPU cache size: %d vs %d", c.Size, other.Size)
-	}
-	return nil
-}
-
 type Benchmark struct {
+	// Counters has the user counters, the numeric values which are not
+	// one of the benchmarkKeys.
+	Counters map[string]float64 `json:"-"`
+	// SingleRun is set by the input formats where every repetition is
+	// one run of a program, e.g. hyperfine, so its iterations are not
+	// worth checking.
+	SingleRun       bool    `json:"-"`
 	Name            string  `json:"name"`
 	RunName         string  `json:"run_name"`
 	RunType         string  `json:"run_type"`
+	AggregateName   string  `json:"aggregate_name"`
 	TimeUnit        string  `json:"time_unit"`
+	Label           string  `json:"label"`
+	ErrorMessage    string  `json:"error_message"`
+	SkipMessage     string  `json:"skip_message"`
+	BigO            string  `json:"big_o"`
 	Repetitions     uint64  `json:"repetitions"`
 	RepetitionIndex uint64  `json:"repetition_index"`
 	Threads         int     `json:"threads"`
 	Iterations      uint64  `json:"iterations"`
+	ComplexityN     int64   `json:"complexity_n"`
 	RealTime        float64 `json:"real_time"`
 	CPUTime         float64 `json:"cpu_time"`
+	RealCoefficient float64 `json:"real_coefficient"`
+	CPUCoefficient  float64 `json:"cpu_coefficient"`
+	RMS             float64 `json:"rms"`
+	ErrorOccurred   bool    `json:"error_occurred"`
+	Skipped         bool    `json:"skipped"`
+}
+
+// benchmarkKeys are the keys of a benchmark written by google benchmark,
+// the other numeric values are user counters, bytes_per_second and
+// items_per_second included.
+var benchmarkKeys = map[string]bool{
+	"name":                      true,
+	"family_index":              true,
+	"per_family_instance_index": true,
+	"run_name":                  true,
+	"run_type":                  true,
+	"repetitions":               true,
+	"repetition_index":          true,
+	"threads":                   true,
+	"iterations":                true,
+	"real_time":                 true,
+	"cpu_time":                  true,
+	"time_unit":                 true,
+	"aggregate_name":            true,
+	"aggregate_unit":            true,
+	"label":                     true,
+	"error_occurred":            true,
+	"error_message":             true,
+	"skipped":                   true,
+	"skip_message":              true,
+	"big_o":                     true,
+	"complexity_n":              true,
+	"real_coefficient":          true,
+	"cpu_coefficient":           true,
+	"rms":                       true,
+}
+
+func (b *Benchmark) UnmarshalJSON(data []byte) error {
+	type benchmark Benchmark
+	if err := json.Unmarshal(data, (*benchmark)(b)); err != nil {
+		return err
+	}
+	// google benchmark writes the benchmarks skipped with
+	// state.SkipWithMessage() as error_occurred=false with the message
+	// in error_message
+	if !b.ErrorOccurred && b.ErrorMessage != "" {
+		b.Skipped = true
+		b.SkipMessage = b.ErrorMessage
+		b.ErrorMessage = ""
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for k, v := range all {
+		if benchmarkKeys[k] {
+			continue
+		}
+		var f float64
+		if err := json.Unmarshal(v, &f); err != nil {
+			continue
+		}
+		if b.Counters == nil {
+			b.Counters = make(map[string]float64)
+		}
+		b.Counters[k] = f
+	}
+	return nil
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBenchmarkUnmarshalJSONCounters(t *testing.T) {
+	data := `{
+  "name": "BM_copy/1024", "family_index": 0, "per_family_instance_index": 0,
+  "run_name": "BM_copy/1024", "run_type": "iteration", "repetitions": 1, "repetition_index": 0,
+  "threads": 1, "iterations": 2000, "real_time": 350.5, "cpu_time": 349.8, "time_unit": "ns",
+  "bytes_per_second": 2.9e9, "items_per_second": 2.8e6, "label": "aligned", "hits": 12
+}`
+	var b Benchmark
+	if err := json.Unmarshal([]byte(data), &b); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"bytes_per_second": 2.9e9,
+		"items_per_second": 2.8e6,
+		"hits":             12,
+	}
+	if !reflect.DeepEqual(b.Counters, want) {
+		t.Errorf("counters = %v, want %v", b.Counters, want)
+	}
+	if b.RealTime != 350.5 || b.Iterations != 2000 || b.Label != "aligned" {
+		t.Errorf("got real_time %v, iterations %d, label %q", b.RealTime, b.Iterations, b.Label)
+	}
+}
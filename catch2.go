@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// catch2Format is the output of Catch2 with --reporter xml.
+//
+// The XML reporter only writes the mean of each benchmark, so one run
+// gives one repetition. The output of several runs can be concatenated
+// in the same file to get more repetitions.
+type catch2Format struct{}
+
+func (catch2Format) Name() string { return "catch2" }
+
+func (catch2Format) Detect(data []byte) bool {
+	return bytes.Contains(data, []byte("<Catch")) &&
+		bytes.Contains(data, []byte("<BenchmarkResults"))
+}
+
+type catch2Benchmark struct {
+	Name       string `xml:"name,attr"`
+	Samples    uint64 `xml:"samples,attr"`
+	Iterations uint64 `xml:"iterations,attr"`
+	Mean       struct {
+		Value float64 `xml:"value,attr"`
+	} `xml:"mean"`
+}
+
+func (catch2Format) Decode(name string, r io.Reader) (Result, error) {
+	var res Result
+	reps := make(map[string]uint64)
+
+	d := xml.NewDecoder(r)
+	testCase := ""
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "TestCase":
+			for _, a := range start.Attr {
+				if a.Name.Local == "name" {
+					testCase = a.Value
+				}
+			}
+		case "BenchmarkResults":
+			var cb catch2Benchmark
+			if err := d.DecodeElement(&cb, &start); err != nil {
+				return Result{}, err
+			}
+			b := Benchmark{
+				Name:       testCase + "/" + cb.Name,
+				RunName:    testCase + "/" + cb.Name,
+				RunType:    "iteration",
+				TimeUnit:   "ns",
+				Threads:    1,
+				Iterations: cb.Samples * cb.Iterations,
+				RealTime:   cb.Mean.Value,
+			}
+			b.RepetitionIndex = reps[b.Name]
+			reps[b.Name]++
+			res.Benchmarks = append(res.Benchmarks, b)
+		}
+	}
+
+	for i := range res.Benchmarks {
+		b := &res.Benchmarks[i]
+		b.Repetitions = reps[b.Name]
+	}
+	return res, nil
+}
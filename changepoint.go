@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A ChangePoint is a result of a trend where the benchmark changed
+// significantly: the results before it and the ones from it on are
+// compared with the Mann-Whitney U-test.
+type ChangePoint struct {
+	Name string
+	Unit string
+	// Index is the index of the first result with the new
+	// distribution, Prev the index of the previous result with the
+	// benchmark.
+	Index int
+	Prev  int
+	Comparison
+	Before Sample
+	After  Sample
+}
+
+// pool returns a sample with the values of all the given samples,
+// without outliers already.
+func pool(samples []*Sample) Sample {
+	var p Sample
+	for _, s := range samples {
+		p.RValues = append(p.RValues, s.RValues...)
+	}
+	sort.Float64s(p.RValues)
+	p.Values = p.RValues
+	p.Min, p.Max = Bounds(p.RValues)
+	p.Mean = Mean(p.RValues)
+	return p
+}
+
+// ChangePoints finds the change points of t with a sliding window: for
+// every result, the window results before it are compared with the
+// window results from it on. Of consecutive significant changes of at
+// least minDelta %, only the most significant is a change point, as the
+// windows around a change overlap it.
+func (t Trend) ChangePoints(window int, minDelta float64) []ChangePoint {
+	var idx []int
+	for i, s := range t.Samples {
+		if s != nil {
+			idx = append(idx, i)
+		}
+	}
+
+	var points []ChangePoint
+	var run []ChangePoint
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		best := run[0]
+		for _, c := range run[1:] {
+			if c.P < best.P || (c.P == best.P && math.Abs(c.Delta) > math.Abs(best.Delta)) {
+				best = c
+			}
+		}
+		points = append(points, best)
+		run = nil
+	}
+
+	for k := 1; k < len(idx); k++ {
+		var before, after []*Sample
+		for j := k - window; j < k; j++ {
+			if j >= 0 {
+				before = append(before, t.Samples[idx[j]])
+			}
+		}
+		for j := k; j < k+window && j < len(idx); j++ {
+			after = append(after, t.Samples[idx[j]])
+		}
+
+		c := ChangePoint{
+			Name:   t.Name,
+			Unit:   t.Unit,
+			Index:  idx[k],
+			Prev:   idx[k-1],
+			Before: pool(before),
+			After:  pool(after),
+		}
+		c.Comparison = c.Before.Compare(c.After)
+		if c.Significant && math.Abs(c.Delta) >= minDelta {
+			run = append(run, c)
+		} else {
+			flush()
+		}
+	}
+	flush()
+
+	return points
+}
+
+// PrintChangePoints prints the change points of what between the labels
+// of the last result before each change and the first one after it,
+// which is the range to bisect.
+func (p Printer) PrintChangePoints(what string, labels []string, points []ChangePoint) error {
+	title := "change points"
+	if what == "real" || what == "cpu" {
+		title += " (" + what + " time)"
+	} else {
+		title += " (" + what + ")"
+	}
+
+	fmt.Fprintf(p.w, "%s\tbetween\tdelta\tnote\tbefore\tafter\n", title)
+	fmt.Fprintf(p.w, "%s\t-------\t-----\t----\t------\t-----\n", strings.Repeat("-", len(title)))
+	for _, c := range points {
+		fmt.Fprintf(p.w, "%s\t%s..%s\t%+.2f%%\t(p=%0.2f n=%d+%d)\t%.2f%s\t%.2f%s\n",
+			c.Name, labels[c.Prev], labels[c.Index], c.Delta,
+			c.P, len(c.Before.RValues), len(c.After.RValues),
+			c.Before.Mean, c.Unit, c.After.Mean, c.Unit)
+	}
+	return p.w.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+// trendSample returns a sample of 10 repetitions around mean, which
+// differ by result r.
+func trendSample(mean float64, r int) *Sample {
+	s := &Sample{}
+	for i := 0; i < 10; i++ {
+		s.Values = append(s.Values, mean+float64((i*7+r*3)%10)*0.1)
+	}
+	sort.Float64s(s.Values)
+	s.ComputeStats()
+	return s
+}
+
+func TestTrendChangePoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		means    []float64 // 0 for a missing result
+		window   int
+		minDelta float64
+		want     [][2]int // Prev, Index
+	}{
+		{
+			name:   "flat",
+			means:  []float64{100, 100, 100, 100, 100, 100, 100, 100},
+			window: 3,
+		},
+		{
+			name:   "step",
+			means:  []float64{100, 100, 100, 100, 100, 120, 120, 120, 120, 120},
+			window: 3,
+			want:   [][2]int{{4, 5}},
+		},
+		{
+			name:   "missing result at the change",
+			means:  []float64{100, 100, 100, 100, 0, 120, 120, 120, 120},
+			window: 3,
+			want:   [][2]int{{3, 5}},
+		},
+		{
+			name:   "step and back",
+			means:  []float64{100, 100, 100, 100, 120, 120, 120, 120, 100, 100, 100, 100},
+			window: 2,
+			want:   [][2]int{{3, 4}, {7, 8}},
+		},
+		{
+			name:     "below min delta",
+			means:    []float64{100, 100, 100, 100, 110, 110, 110, 110},
+			window:   3,
+			minDelta: 20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := Trend{Name: "BM_a", Unit: "ns"}
+			for r, m := range tt.means {
+				if m == 0 {
+					tr.Samples = append(tr.Samples, nil)
+					continue
+				}
+				tr.Samples = append(tr.Samples, trendSample(m, r))
+			}
+
+			var got [][2]int
+			for _, c := range tr.ChangePoints(tt.window, tt.minDelta) {
+				got = append(got, [2]int{c.Prev, c.Index})
+				if !c.Significant {
+					t.Errorf("change point %d..%d is not significant", c.Prev, c.Index)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got change points %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got change points %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
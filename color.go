@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// The ANSI colors of the output. They all have the same length, so that
+// the columns of the tabwriter stay aligned when every cell of a column
+// is colored, colorNone included.
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorNone   = "\x1b[39m"
+	colorReset  = "\x1b[0m"
+)
+
+// useColor reports whether the output is colored for the -color mode:
+// always, never or auto, which colors only a terminal and respects
+// NO_COLOR.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		fi, err := os.Stdout.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid -color '%s', expected auto, always or never", mode)
+}
+
+// color returns s in the given color if p prints colors.
+func (p Printer) color(code, s string) string {
+	if !p.Color {
+		return s
+	}
+	return code + s + colorReset
+}
+
+// deltaColor returns the color of a change: for times, red if it is
+// slower and green if it is faster, for other metrics, which can be
+// better higher or lower, yellow.
+func deltaColor(c Comparison, time bool) string {
+	switch {
+	case !c.Significant || c.Delta == 0:
+		return colorNone
+	case !time:
+		return colorYellow
+	case c.Delta > 0:
+		return colorRed
+	}
+	return colorGreen
+}
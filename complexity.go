@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Complexity is the asymptotic complexity computed by google benchmark
+// for a family registered with ->Complexity(), from its _BigO and _RMS
+// entries.
+type Complexity struct {
+	Family          string
+	BigO            string
+	TimeUnit        string
+	RealCoefficient float64
+	CPUCoefficient  float64
+	RMS             float64
+}
+
+// Class returns the complexity in big O notation, e.g. O(NlgN).
+func (c Complexity) Class() string {
+	if strings.HasPrefix(c.BigO, "(") {
+		return "O" + c.BigO
+	}
+	return "O(" + c.BigO + ")"
+}
+
+// GetComplexities returns the complexities found in benchmarks.
+func GetComplexities(benchmarks []Benchmark, filter Filter) []Complexity {
+	var cs []Complexity
+	index := make(map[string]int)
+	for _, b := range benchmarks {
+		if b.AggregateName != "BigO" && b.AggregateName != "RMS" {
+			continue
+		}
+		runName := b.RunName
+		if runName == "" {
+			runName = strings.TrimSuffix(strings.TrimSuffix(b.Name, "_BigO"), "_RMS")
+		}
+		key := ParseBenchName(runName, b.Threads)
+		if !filter.Match(b.Name, key) {
+			continue
+		}
+		family := key.String()
+		i, ok := index[family]
+		if !ok {
+			i = len(cs)
+			index[family] = i
+			cs = append(cs, Complexity{Family: family})
+		}
+		if b.AggregateName == "BigO" {
+			cs[i].BigO = b.BigO
+			cs[i].TimeUnit = b.TimeUnit
+			cs[i].RealCoefficient = b.RealCoefficient
+			cs[i].CPUCoefficient = b.CPUCoefficient
+		} else {
+			cs[i].RMS = b.RMS
+		}
+	}
+	return cs
+}
+
+// PrintComplexity compares the old and new complexities using the
+// coefficients of what, which is "real" or "cpu".
+//
+// The coefficients can be compared only if the complexity class is the
+// same, otherwise the change of class is reported.
+func (p Printer) PrintComplexity(what string, old, new []Complexity) error {
+	if what != "real" && what != "cpu" {
+		return fmt.Errorf("unknown what value '%s'", what)
+	}
+
+	title := what + " complexity"
+	fmt.Fprintf(p.w, "%s\tdelta\tnote\told\tnew\n", title)
+	fmt.Fprintf(p.w, "%s\t-----\t----\t---\t---\n", strings.Repeat("-", len(title)))
+
+	for _, o := range old {
+		var n *Complexity
+		for i := range new {
+			if new[i].Family == o.Family {
+				n = &new[i]
+			}
+		}
+		if n == nil {
+			continue
+		}
+
+		oc, nc := o.RealCoefficient, n.RealCoefficient
+		if what == "cpu" {
+			oc, nc = o.CPUCoefficient, n.CPUCoefficient
+		}
+
+		delta := "~"
+		note := fmt.Sprintf("(rms=%.0f%%+%.0f%%)", o.RMS*100, n.RMS*100)
+		switch {
+		case o.BigO != n.BigO:
+			delta = "CHANGED"
+			note = fmt.Sprintf("(%s -> %s)", o.Class(), n.Class())
+		case o.TimeUnit != n.TimeUnit:
+			return fmt.Errorf(
+				"complexities have different time units: old=%s, new=%s",
+				o.TimeUnit, n.TimeUnit)
+		case oc != 0:
+			delta = fmt.Sprintf("%+.2f%%", (nc-oc)/oc*100.0)
+		}
+
+		fmt.Fprintf(p.w, "%s\t%s\t%s", n.Family, delta, note)
+		fmt.Fprintf(p.w, "\t%.2f%s %s\t%.2f%s %s\n", oc, o.TimeUnit, o.Class(), nc, n.TimeUnit, n.Class())
+	}
+
+	return p.w.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// A compression is a compressed file format, identified by its magic
+// bytes. Formats not supported by the standard library are decompressed
+// with an external command.
+type compression struct {
+	name  string
+	magic []byte
+	cmd   []string
+	open  func(r io.Reader) (io.Reader, error)
+}
+
+var compressions = []compression{
+	{
+		name:  "gzip",
+		magic: []byte{0x1f, 0x8b},
+		open: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		name:  "bzip2",
+		magic: []byte("BZh"),
+		open: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	},
+	{
+		name:  "zstd",
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		cmd:   []string{"zstd", "-dc"},
+	},
+	{
+		name:  "xz",
+		magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		cmd:   []string{"xz", "-dc"},
+	},
+	{
+		name:  "lz4",
+		magic: []byte{0x04, 0x22, 0x4d, 0x18},
+		cmd:   []string{"lz4", "-dc"},
+	},
+}
+
+// decompress returns a reader with the decompressed contents of r, if r
+// starts with the magic bytes of a known compression format, or r as is.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	for _, c := range compressions {
+		head, _ := br.Peek(len(c.magic))
+		if !bytes.Equal(head, c.magic) {
+			continue
+		}
+		if c.open != nil {
+			return c.open(br)
+		}
+		return decompressCmd(c, br)
+	}
+	return br, nil
+}
+
+func decompressCmd(c compression, r io.Reader) (io.Reader, error) {
+	path, err := exec.LookPath(c.cmd[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s compressed input needs the %s command: %w", c.name, c.cmd[0], err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, c.cmd[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", c.cmd[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return &stdout, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The levels of a context check: a difference in a field with level
+// ctxError fails the comparison, one with level ctxWarn is printed and
+// one with level ctxIgnore is ignored.
+const (
+	ctxError  = "error"
+	ctxWarn   = "warn"
+	ctxIgnore = "ignore"
+)
+
+// A contextField is a field of the context which can be compared.
+type contextField struct {
+	name  string
+	level string
+	value func(c Context) string
+}
+
+// contextFields are the compared fields, with their default levels.
+// The custom context(--benchmark_context) has the level of "custom",
+// unless its key is given a level.
+var contextFields = []contextField{
+	{"date", ctxIgnore, func(c Context) string { return c.Date }},
+	{"host_name", ctxIgnore, func(c Context) string { return c.Hostname }},
+	{"executable", ctxIgnore, func(c Context) string { return c.Executable }},
+	{"num_cpus", ctxError, func(c Context) string { return strconv.Itoa(c.NumCPUs) }},
+	{"mhz_per_cpu", ctxError, func(c Context) string { return strconv.Itoa(c.MHzPerCPU) }},
+	{"cpu_scaling_enabled", ctxError, func(c Context) string { return strconv.FormatBool(c.CPUScalingEnabled) }},
+	{"caches", ctxError, func(c Context) string { return formatCaches(c.Caches) }},
+	{"load_avg", ctxIgnore, func(c Context) string { return formatLoadAvg(c.LoadAvg) }},
+	{"library_build_type", ctxWarn, func(c Context) string { return c.LibraryBuildType }},
+	{"library_version", ctxWarn, func(c Context) string { return c.LibraryVersion }},
+	{"json_schema_version", ctxIgnore, func(c Context) string { return strconv.Itoa(c.JSONSchemaVersion) }},
+}
+
+const ctxCustomDefault = ctxWarn
+
+func formatCaches(caches []Cache) string {
+	var s []string
+	for _, c := range caches {
+		s = append(s, fmt.Sprintf("L%d %s %d KiB (x%d)", c.Level, c.Type, c.Size/1024, c.NumSharing))
+	}
+	return strings.Join(s, ", ")
+}
+
+func formatLoadAvg(load []float64) string {
+	var s []string
+	for _, l := range load {
+		s = append(s, strconv.FormatFloat(l, 'f', 2, 64))
+	}
+	return strings.Join(s, " ")
+}
+
+// A ContextDiff is the old and new value of a context field.
+type ContextDiff struct {
+	Field string `json:"field"`
+	Level string `json:"level"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Differs reports whether the old and new values are different.
+func (d ContextDiff) Differs() bool {
+	return d.Old != d.New
+}
+
+// checkContextLevels validates the levels given with -ctx.
+func checkContextLevels(levels map[string]string) error {
+	for k, v := range levels {
+		if v != ctxError && v != ctxWarn && v != ctxIgnore {
+			return fmt.Errorf("invalid level '%s' for context field '%s', expected %s, %s or %s",
+				v, k, ctxError, ctxWarn, ctxIgnore)
+		}
+	}
+	return nil
+}
+
+// DiffContexts compares all the fields of the old and new contexts.
+// levels overrides the default level of the fields.
+func DiffContexts(old, new Context, levels map[string]string) []ContextDiff {
+	var diffs []ContextDiff
+	for _, f := range contextFields {
+		level := f.level
+		if l, ok := levels[f.name]; ok {
+			level = l
+		}
+		diffs = append(diffs, ContextDiff{
+			Field: f.name,
+			Level: level,
+			Old:   f.value(old),
+			New:   f.value(new),
+		})
+	}
+
+	var keys []string
+	for k := range old.Custom {
+		keys = append(keys, k)
+	}
+	for k := range new.Custom {
+		if _, ok := old.Custom[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		level := ctxCustomDefault
+		if l, ok := levels["custom"]; ok {
+			level = l
+		}
+		if l, ok := levels[k]; ok {
+			level = l
+		}
+		diffs = append(diffs, ContextDiff{
+			Field: k,
+			Level: level,
+			Old:   old.Custom[k],
+			New:   new.Custom[k],
+		})
+	}
+	return diffs
+}
+
+// contextErrors returns the fields with level error which differ.
+func contextErrors(diffs []ContextDiff) []string {
+	var fields []string
+	for _, d := range diffs {
+		if d.Level == ctxError && d.Differs() {
+			fields = append(fields, d.Field)
+		}
+	}
+	return fields
+}
+
+// PrintContext prints the context fields side by side: all of them if
+// all is true, otherwise only the ones which differ and are not
+// ignored. It prints nothing if there is nothing to print.
+func (p Printer) PrintContext(diffs []ContextDiff, all bool) (bool, error) {
+	var rows []ContextDiff
+	for _, d := range diffs {
+		if all || (d.Differs() && d.Level != ctxIgnore) {
+			rows = append(rows, d)
+		}
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+
+	title := "context"
+	fmt.Fprintf(p.w, "%s\tlevel\told\tnew\n", title)
+	fmt.Fprintf(p.w, "%s\t-----\t---\t---\n", strings.Repeat("-", len(title)))
+	for _, d := range rows {
+		level := "="
+		if d.Differs() {
+			level = d.Level
+		}
+		fmt.Fprintf(p.w, "%s\t%s\t%s\t%s\n", d.Field, level, orDash(d.Old), orDash(d.New))
+	}
+
+	return true, p.w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// criterionFormat is one of the files written by Rust Criterion in
+// target/criterion/<benchmark>/new: sample.json, which has the time of
+// every sample, or estimates.json, which only has the mean.
+//
+// The benchmark name is taken from the file path. To read all the
+// benchmarks at once, give the target/criterion directory instead(see
+// readCriterionDir).
+type criterionFormat struct{}
+
+type criterionSample struct {
+	Iters []float64 `json:"iters"`
+	Times []float64 `json:"times"`
+}
+
+type criterionEstimates struct {
+	Mean struct {
+		PointEstimate float64 `json:"point_estimate"`
+	} `json:"mean"`
+}
+
+func (criterionFormat) Name() string { return "criterion" }
+
+func (criterionFormat) Detect(data []byte) bool {
+	m := jsonObject(data)
+	_, iters := m["iters"]
+	_, times := m["times"]
+	_, mean := m["mean"]
+	_, mad := m["median_abs_dev"]
+	return (iters && times) || (mean && mad)
+}
+
+func (criterionFormat) Decode(name string, r io.Reader) (Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+	return decodeCriterion(criterionName(name), data)
+}
+
+// decodeCriterion decodes the contents of sample.json or
+// estimates.json for the benchmark called name.
+func decodeCriterion(name string, data []byte) (Result, error) {
+	var res Result
+
+	if _, ok := jsonObject(data)["times"]; ok {
+		var s criterionSample
+		if err := json.Unmarshal(data, &s); err != nil {
+			return Result{}, err
+		}
+		for i := range s.Times {
+			if i >= len(s.Iters) || s.Iters[i] == 0 {
+				continue
+			}
+			res.Benchmarks = append(res.Benchmarks, Benchmark{
+				Name:            name,
+				RunName:         name,
+				RunType:         "iteration",
+				TimeUnit:        "ns",
+				Threads:         1,
+				Repetitions:     uint64(len(s.Times)),
+				RepetitionIndex: uint64(i),
+				Iterations:      uint64(s.Iters[i]),
+				// times are the total duration of all the iterations
+				RealTime: s.Times[i] / s.Iters[i],
+			})
+		}
+		return res, nil
+	}
+
+	var e criterionEstimates
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Result{}, err
+	}
+	res.Benchmarks = append(res.Benchmarks, Benchmark{
+		Name:        name,
+		RunName:     name,
+		RunType:     "iteration",
+		TimeUnit:    "ns",
+		Threads:     1,
+		Repetitions: 1,
+		RealTime:    e.Mean.PointEstimate,
+	})
+	return res, nil
+}
+
+// criterionName returns the benchmark name from the path of one of its
+// files: target/criterion/group/function/new/sample.json gives
+// group/function.
+func criterionName(path string) string {
+	dir := filepath.Dir(path)
+	if b := filepath.Base(dir); b == "new" || b == "base" {
+		dir = filepath.Dir(dir)
+	}
+	parts := strings.Split(filepath.ToSlash(dir), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == "criterion" {
+			return strings.Join(parts[i+1:], "/")
+		}
+	}
+	return parts[len(parts)-1]
+}
+
+// readCriterionDir reads all the benchmarks from a Criterion output
+// directory, using the full_id from new/benchmark.json as name and the
+// times from new/sample.json.
+func readCriterionDir(root string) (Result, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == "sample.json" && filepath.Base(filepath.Dir(path)) == "new" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	sort.Strings(dirs)
+
+	var res Result
+	for _, dir := range dirs {
+		name := criterionName(filepath.Join(dir, "sample.json"))
+		if data, err := os.ReadFile(filepath.Join(dir, "benchmark.json")); err == nil {
+			var info struct {
+				FullID string `json:"full_id"`
+			}
+			if json.Unmarshal(data, &info) == nil && info.FullID != "" {
+				name = info.FullID
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "sample.json"))
+		if err != nil {
+			return Result{}, err
+		}
+		r, err := decodeCriterion(name, data)
+		if err != nil {
+			return Result{}, err
+		}
+		res.Benchmarks = append(res.Benchmarks, r.Benchmarks...)
+	}
+	return res, nil
+}
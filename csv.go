@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the fixed columns written by the google benchmark CSV
+// reporter, in order. User counters follow them as extra columns.
+var csvColumns = []string{
+	"name",
+	"iterations",
+	"real_time",
+	"cpu_time",
+	"time_unit",
+	"bytes_per_second",
+	"items_per_second",
+	"label",
+	"error_occurred",
+	"error_message",
+}
+
+// csvAggregates are the name suffixes the CSV reporter uses for
+// aggregate rows, since the CSV output has no run_type column.
+var csvAggregates = []string{"mean", "median", "stddev", "cv", "BigO", "RMS"}
+
+// csvFormat is the output of google benchmark with
+// --benchmark_out_format=csv.
+type csvFormat struct{}
+
+func (csvFormat) Name() string { return "csv" }
+
+func (csvFormat) Detect(data []byte) bool {
+	header := strings.Join(csvColumns[:2], ",")
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, header) {
+			return true
+		}
+	}
+	return false
+}
+
+func (csvFormat) Decode(name string, r io.Reader) (Result, error) {
+	return decodeCSV(r)
+}
+
+// decodeCSV reads the output of a benchmark run with
+// --benchmark_format=csv or --benchmark_out_format=csv.
+func decodeCSV(r io.Reader) (Result, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	// the console output may contain the context before the header, skip it
+	var header []string
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return Result{}, errors.New("csv: missing header")
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				continue
+			}
+			return Result{}, err
+		}
+		if len(rec) > 0 && rec[0] == "name" {
+			header = rec
+			break
+		}
+	}
+	if len(header) < len(csvColumns) {
+		return Result{}, fmt.Errorf("csv: header has %d columns, expected at least %d", len(header), len(csvColumns))
+	}
+	for i, c := range csvColumns {
+		if header[i] != c {
+			return Result{}, fmt.Errorf("csv: unexpected column %d: %q instead of %q", i+1, header[i], c)
+		}
+	}
+	counters := header[len(csvColumns):]
+
+	var res Result
+	reps := make(map[string]uint64)
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		b, err := csvBenchmark(rec, counters)
+		if err != nil {
+			return Result{}, fmt.Errorf("csv: record %d: %w", line, err)
+		}
+		if b.RunType == "iteration" {
+			b.RepetitionIndex = reps[b.Name]
+			reps[b.Name]++
+		}
+		res.Benchmarks = append(res.Benchmarks, b)
+	}
+	units := make(map[string]string)
+	for i := range res.Benchmarks {
+		b := &res.Benchmarks[i]
+		if b.RunType == "iteration" {
+			b.Repetitions = reps[b.Name]
+			units[ParseBenchName(b.RunName, 1).FamilyName()] = b.TimeUnit
+		}
+	}
+	// the complexity rows don't have the time unit, use the one of the
+	// family they are computed for
+	for i := range res.Benchmarks {
+		b := &res.Benchmarks[i]
+		if b.AggregateName == "BigO" {
+			b.TimeUnit = units[b.RunName]
+		}
+	}
+	return res, nil
+}
+
+func csvBenchmark(rec []string, counters []string) (Benchmark, error) {
+	field := func(i int) string {
+		if i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+	b := Benchmark{
+		Name:         field(0),
+		RunName:      field(0),
+		RunType:      "iteration",
+		TimeUnit:     field(4),
+		Label:        field(7),
+		ErrorMessage: field(9),
+		Threads:      1,
+	}
+	if b.Name == "" {
+		return b, errors.New("empty name")
+	}
+	for _, a := range csvAggregates {
+		if strings.HasSuffix(b.Name, "_"+a) {
+			b.RunType = "aggregate"
+			b.AggregateName = a
+			b.RunName = strings.TrimSuffix(b.Name, "_"+a)
+			break
+		}
+	}
+	if i := strings.LastIndex(b.RunName, "/threads:"); i != -1 {
+		n, err := strconv.Atoi(b.RunName[i+len("/threads:"):])
+		if err == nil {
+			b.Threads = n
+		}
+	}
+
+	var err error
+	parseFloat := func(s string) float64 {
+		if s == "" || err != nil {
+			return 0
+		}
+		var v float64
+		v, err = strconv.ParseFloat(s, 64)
+		return v
+	}
+
+	// error_occurred is set only for the benchmarks skipped with
+	// state.SkipWithError(true) or state.SkipWithMessage(false)
+	if s := field(8); s != "" {
+		b.ErrorOccurred, err = strconv.ParseBool(s)
+		if err != nil {
+			return b, err
+		}
+		if !b.ErrorOccurred {
+			b.Skipped = true
+			b.SkipMessage = b.ErrorMessage
+			b.ErrorMessage = ""
+		}
+		return b, nil
+	}
+
+	if s := field(1); s != "" {
+		b.Iterations, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return b, err
+		}
+	}
+	b.RealTime = parseFloat(field(2))
+	b.CPUTime = parseFloat(field(3))
+	switch b.AggregateName {
+	case "BigO":
+		// the time unit column has the complexity and the times are
+		// the coefficients
+		b.BigO = b.TimeUnit
+		b.RealCoefficient = b.RealTime
+		b.CPUCoefficient = b.CPUTime
+	case "RMS":
+		b.RMS = b.CPUTime
+	}
+	counter := func(name, s string) {
+		if s == "" {
+			return
+		}
+		if b.Counters == nil {
+			b.Counters = make(map[string]float64)
+		}
+		b.Counters[name] = parseFloat(s)
+	}
+	// the throughputs set with state.SetBytesProcessed() and
+	// state.SetItemsProcessed() are counters like the user ones
+	counter(csvColumns[5], field(5))
+	counter(csvColumns[6], field(6))
+	for i, name := range counters {
+		counter(name, field(len(csvColumns)+i))
+	}
+	return b, err
+}
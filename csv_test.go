@@ -0,0 +1,147 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Benchmark
+	}{
+		{
+			name: "repetitions",
+			input: `2023-02-11T10:00:00+02:00
+Running ./bench
+Run on (8 X 2400 MHz CPU s)
+name,iterations,real_time,cpu_time,time_unit,bytes_per_second,items_per_second,label,error_occurred,error_message
+"BM_copy/1024",2000000,350.5,349.8,ns,2.92e+09,,,,
+"BM_copy/1024",2000000,351.5,350.8,ns,2.91e+09,,,,
+"BM_copy/1024_mean",2,351,350.3,ns,2.915e+09,,,,
+"BM_push/real_time/threads:4",800000,25.6,101.2,ns,,3.9e+07,"lock free",,
+`,
+			want: []Benchmark{
+				{
+					Name: "BM_copy/1024", RunName: "BM_copy/1024", RunType: "iteration", TimeUnit: "ns",
+					Threads: 1, Iterations: 2000000, RealTime: 350.5, CPUTime: 349.8,
+					Repetitions: 2, RepetitionIndex: 0,
+					Counters: map[string]float64{"bytes_per_second": 2.92e9},
+				},
+				{
+					Name: "BM_copy/1024", RunName: "BM_copy/1024", RunType: "iteration", TimeUnit: "ns",
+					Threads: 1, Iterations: 2000000, RealTime: 351.5, CPUTime: 350.8,
+					Repetitions: 2, RepetitionIndex: 1,
+					Counters: map[string]float64{"bytes_per_second": 2.91e9},
+				},
+				{
+					Name: "BM_copy/1024_mean", RunName: "BM_copy/1024", RunType: "aggregate", AggregateName: "mean",
+					TimeUnit: "ns", Threads: 1, Iterations: 2, RealTime: 351, CPUTime: 350.3,
+					Counters: map[string]float64{"bytes_per_second": 2.915e9},
+				},
+				{
+					Name: "BM_push/real_time/threads:4", RunName: "BM_push/real_time/threads:4", RunType: "iteration",
+					TimeUnit: "ns", Label: "lock free", Threads: 4, Iterations: 800000, RealTime: 25.6, CPUTime: 101.2,
+					Repetitions: 1,
+					Counters:    map[string]float64{"items_per_second": 3.9e7},
+				},
+			},
+		},
+		{
+			name: "skipped",
+			input: `name,iterations,real_time,cpu_time,time_unit,bytes_per_second,items_per_second,label,error_occurred,error_message
+"BM_file",,,,,,,,false,"no input file"
+"BM_file",,,,,,,,true,"read failed, errno 5"
+`,
+			want: []Benchmark{
+				{
+					Name: "BM_file", RunName: "BM_file", RunType: "iteration", Threads: 1,
+					Skipped: true, SkipMessage: "no input file", Repetitions: 2, RepetitionIndex: 0,
+				},
+				{
+					Name: "BM_file", RunName: "BM_file", RunType: "iteration", Threads: 1,
+					ErrorOccurred: true, ErrorMessage: "read failed, errno 5", Repetitions: 2, RepetitionIndex: 1,
+				},
+			},
+		},
+		{
+			name: "complexity",
+			input: `name,iterations,real_time,cpu_time,time_unit,bytes_per_second,items_per_second,label,error_occurred,error_message
+"BM_sort<int>/8",100000,40.5,40.1,us,,,,,
+"BM_sort<int>/64",10000,410.2,409.8,us,,,,,
+"BM_sort<int>_BigO",,6.34,6.31,NlgN,,,,,
+"BM_sort<int>_RMS",,0.02,0.02,,,,,,
+`,
+			want: []Benchmark{
+				{
+					Name: "BM_sort<int>/8", RunName: "BM_sort<int>/8", RunType: "iteration", TimeUnit: "us",
+					Threads: 1, Iterations: 100000, RealTime: 40.5, CPUTime: 40.1, Repetitions: 1,
+				},
+				{
+					Name: "BM_sort<int>/64", RunName: "BM_sort<int>/64", RunType: "iteration", TimeUnit: "us",
+					Threads: 1, Iterations: 10000, RealTime: 410.2, CPUTime: 409.8, Repetitions: 1,
+				},
+				{
+					Name: "BM_sort<int>_BigO", RunName: "BM_sort<int>", RunType: "aggregate", AggregateName: "BigO",
+					TimeUnit: "us", BigO: "NlgN", Threads: 1, RealTime: 6.34, CPUTime: 6.31,
+					RealCoefficient: 6.34, CPUCoefficient: 6.31,
+				},
+				{
+					Name: "BM_sort<int>_RMS", RunName: "BM_sort<int>", RunType: "aggregate", AggregateName: "RMS",
+					Threads: 1, RealTime: 0.02, CPUTime: 0.02, RMS: 0.02,
+				},
+			},
+		},
+		{
+			name: "quoted counters",
+			input: `name,iterations,real_time,cpu_time,time_unit,bytes_per_second,items_per_second,label,error_occurred,error_message,"hits","miss rate"
+"BM_cache/4096",5000,120,119.5,ns,,,,,,4000,0.25
+"BM_cache/8192",2500,260,259,ns,,,,,,,0.5
+`,
+			want: []Benchmark{
+				{
+					Name: "BM_cache/4096", RunName: "BM_cache/4096", RunType: "iteration", TimeUnit: "ns",
+					Threads: 1, Iterations: 5000, RealTime: 120, CPUTime: 119.5, Repetitions: 1,
+					Counters: map[string]float64{"hits": 4000, "miss rate": 0.25},
+				},
+				{
+					Name: "BM_cache/8192", RunName: "BM_cache/8192", RunType: "iteration", TimeUnit: "ns",
+					Threads: 1, Iterations: 2500, RealTime: 260, CPUTime: 259, Repetitions: 1,
+					Counters: map[string]float64{"miss rate": 0.5},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := decodeCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.Benchmarks, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", res.Benchmarks, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no header", "\"BM_a\",10,1,1,ns,,,,,\n"},
+		{"short header", "name,iterations,real_time\n"},
+		{"bad iterations", "name,iterations,real_time,cpu_time,time_unit,bytes_per_second,items_per_second,label,error_occurred,error_message\n\"BM_a\",ten,1,1,ns,,,,,\n"},
+		{"bad counter", "name,iterations,real_time,cpu_time,time_unit,bytes_per_second,items_per_second,label,error_occurred,error_message,foo\n\"BM_a\",10,1,1,ns,,,,,,x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCSV(strings.NewReader(tt.input)); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// diffOptions are the options of the comparison of two results, shared
+// by all the commands which compare results.
+type diffOptions struct {
+	noCtxCheck       bool
+	ctxDiff          bool
+	strict           bool
+	maxLoad          float64
+	minReps          int
+	minIters         uint64
+	ctxLevels        paramsFlag
+	withCPUTime      bool
+	withCounters     bool
+	complexity       bool
+	fit              bool
+	fitCustom        string
+	threads          bool
+	threadsThreshold float64
+	group            bool
+	collapse         bool
+	renames          renamesFlag
+	renameFile       string
+	plots            string
+	dist             string
+	color            string
+	sort             string
+	onlySignificant  bool
+	filterOptions
+
+	// set by init
+	models   []ComplexityModel
+	useColor bool
+}
+
+// filterOptions are the options which select the benchmarks.
+type filterOptions struct {
+	filterRe string
+	family   string
+	params   paramsFlag
+
+	// set by init
+	filter Filter
+}
+
+// register adds the flags of the options to fs.
+func (o *filterOptions) register(fs *flag.FlagSet) {
+	o.params = make(paramsFlag)
+
+	fs.StringVar(&o.filterRe, "filter", "", "select only the benchmarks with names that match the given regex")
+	fs.StringVar(&o.family, "family", "", "select only the benchmarks with family names(name without arguments) that match the given regex")
+	fs.Var(o.params, "param", "select only the benchmarks with the given parameter, as name=value(can be repeated)")
+}
+
+// init compiles the filter after the flags are parsed.
+func (o *filterOptions) init() error {
+	o.filter = Filter{Params: o.params}
+	if o.filterRe != "" {
+		re, err := regexp.Compile(o.filterRe)
+		if err != nil {
+			return err
+		}
+		o.filter.Name = re
+	}
+	if o.family != "" {
+		re, err := regexp.Compile(o.family)
+		if err != nil {
+			return err
+		}
+		o.filter.Family = re
+	}
+	return nil
+}
+
+// register adds the flags of the options to fs.
+func (o *diffOptions) register(fs *flag.FlagSet) {
+	o.ctxLevels = make(paramsFlag)
+
+	fs.BoolVar(&o.noCtxCheck, "no-ctx", false, "don't compare benchmark contexts")
+	fs.Var(o.ctxLevels, "ctx", "set the level of a context field, as field=error|warn|ignore(can be repeated)")
+	fs.BoolVar(&o.ctxDiff, "ctx-diff", false, "print all the context fields side by side")
+	fs.BoolVar(&o.strict, "strict", false, "fail if the environment of the benchmarks is not suitable for a comparison")
+	fs.IntVar(&o.minReps, "min-reps", 5, "the number of repetitions below which a benchmark is reported")
+	fs.Uint64Var(&o.minIters, "min-iters", 100, "the number of iterations below which a benchmark is reported")
+	fs.Float64Var(&o.maxLoad, "max-load", 0.5, "the load average per CPU above which the environment is considered noisy")
+	fs.BoolVar(&o.withCPUTime, "with-cpu", false, "compare also CPU time")
+	fs.BoolVar(&o.withCounters, "with-counters", false, "compare also user counters")
+	fs.BoolVar(&o.complexity, "complexity", false, "compare also the asymptotic complexities(benchmarks with ->Complexity())")
+	fs.BoolVar(&o.fit, "fit", false, "fit the times of every family to complexity models and compare the best fits")
+	fs.StringVar(&o.fitCustom, "fit-custom", "", "with -fit, try also the given model, e.g. N^1.5 or N*lgN^2")
+	fs.BoolVar(&o.threads, "threads", false, "compare how the multithreaded benchmarks scale with the number of threads")
+	fs.Float64Var(&o.threadsThreshold, "threads-threshold", 0.1, "with -threads, the drop in efficiency reported as a scaling regression")
+	fs.BoolVar(&o.group, "group", false, "group the benchmarks by family and print a summary for each family")
+	fs.BoolVar(&o.collapse, "collapse", false, "with -group, print only the summary of the families without significant changes")
+	fs.StringVar(&o.color, "color", "auto", "color the changes: auto(only on a terminal, unless NO_COLOR is set), always or never")
+	fs.StringVar(&o.sort, "sort", "", "sort the rows by delta, p, name or time(largest first)")
+	fs.BoolVar(&o.onlySignificant, "significant", false, "print only the rows with significant changes")
+	fs.StringVar(&o.dist, "dist", "", "draw the distributions of old and new in every row: box(box plots) or hist(histograms)")
+	fs.StringVar(&o.plots, "plots", "", "write SVG plots of the distributions of every benchmark and an index.html to the given directory")
+	o.filterOptions.register(fs)
+	fs.Var(&o.renames, "rename", "rename the old benchmarks before matching them with the new ones, as regex=>replacement(can be repeated)")
+	fs.StringVar(&o.renameFile, "rename-file", "", "read renames from the given file, one regex=>replacement per line")
+}
+
+// init validates the options after the flags are parsed.
+func (o *diffOptions) init() error {
+	if err := o.filterOptions.init(); err != nil {
+		return err
+	}
+
+	if o.dist != "" && o.dist != "box" && o.dist != "hist" {
+		return fmt.Errorf("invalid -dist '%s', expected box or hist", o.dist)
+	}
+
+	switch o.sort {
+	case "", "delta", "p", "name", "time":
+	default:
+		return fmt.Errorf("invalid -sort '%s', expected delta, p, name or time", o.sort)
+	}
+
+	var err error
+	o.useColor, err = useColor(o.color)
+	if err != nil {
+		return err
+	}
+
+	if err := checkContextLevels(o.ctxLevels); err != nil {
+		return err
+	}
+
+	o.models = complexityModels
+	if o.fitCustom != "" {
+		m, err := parseComplexityModel(o.fitCustom)
+		if err != nil {
+			return err
+		}
+		o.models = append(o.models[:len(o.models):len(o.models)], m)
+	}
+
+	if o.renameFile != "" {
+		r, err := loadRenames(o.renameFile)
+		if err != nil {
+			return err
+		}
+		o.renames = append(o.renames, r...)
+	}
+
+	return nil
+}
+
+// diff compares the old and new results and prints the comparison.
+func diff(oldRes, newRes Result, o *diffOptions) error {
+	applyRenames(o.renames, oldRes.Benchmarks)
+
+	warnings := preflight(oldRes.Context, newRes.Context, o.maxLoad)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	if o.strict && len(warnings) > 0 {
+		return errors.New("environment check failed(see the warnings above)")
+	}
+
+	printer := Printer{
+		w:        tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0),
+		Group:    o.group,
+		Collapse: o.collapse,
+		Dist:     o.dist,
+		Color:    o.useColor,
+		Sort:     o.sort,
+
+		OnlySignificant: o.onlySignificant,
+	}
+
+	if !o.noCtxCheck {
+		diffs := DiffContexts(oldRes.Context, newRes.Context, o.ctxLevels)
+		printed, err := printer.PrintContext(diffs, o.ctxDiff)
+		if err != nil {
+			return err
+		}
+		if printed {
+			fmt.Fprintln(printer.w)
+		}
+		if fields := contextErrors(diffs); len(fields) > 0 {
+			return fmt.Errorf("context check failed: different %s(use -ctx field=warn to allow it)",
+				strings.Join(fields, ", "))
+		}
+	}
+
+	oldMetrics := GetMetrics(oldRes.Benchmarks, o.filter)
+	newMetrics := GetMetrics(newRes.Benchmarks, o.filter)
+
+	if err := printer.Print("real", oldMetrics, newMetrics); err != nil {
+		return err
+	}
+
+	if o.withCPUTime {
+		fmt.Fprintln(printer.w)
+		if err := printer.Print("cpu", oldMetrics, newMetrics); err != nil {
+			return err
+		}
+	}
+
+	if f := failures(oldMetrics, newMetrics); len(f) > 0 {
+		fmt.Fprintln(printer.w)
+		if err := printer.PrintFailures(f); err != nil {
+			return err
+		}
+	}
+
+	if d := Validate(oldMetrics, newMetrics, o.minReps, o.minIters); len(d) > 0 {
+		fmt.Fprintln(printer.w)
+		if err := printer.PrintDiagnostics(d); err != nil {
+			return err
+		}
+	}
+
+	if o.complexity {
+		oldComplexities := GetComplexities(oldRes.Benchmarks, o.filter)
+		newComplexities := GetComplexities(newRes.Benchmarks, o.filter)
+		fmt.Fprintln(printer.w)
+		if err := printer.PrintComplexity("real", oldComplexities, newComplexities); err != nil {
+			return err
+		}
+		if o.withCPUTime {
+			fmt.Fprintln(printer.w)
+			if err := printer.PrintComplexity("cpu", oldComplexities, newComplexities); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.fit {
+		fmt.Fprintln(printer.w)
+		if err := printer.PrintFit("real", oldMetrics, newMetrics, o.models); err != nil {
+			return err
+		}
+		if o.withCPUTime {
+			fmt.Fprintln(printer.w)
+			if err := printer.PrintFit("cpu", oldMetrics, newMetrics, o.models); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.threads {
+		fmt.Fprintln(printer.w)
+		if err := printer.PrintScaling(oldMetrics, newMetrics, o.threadsThreshold); err != nil {
+			return err
+		}
+	}
+
+	if o.withCounters {
+		for _, name := range CounterNames(oldMetrics) {
+			fmt.Fprintln(printer.w)
+			if err := printer.Print(name, oldMetrics, newMetrics); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.plots != "" {
+		whats := []string{"real"}
+		if o.withCPUTime {
+			whats = append(whats, "cpu")
+		}
+		if o.withCounters {
+			whats = append(whats, CounterNames(oldMetrics)...)
+		}
+		if err := WritePlots(o.plots, whats, oldMetrics, newMetrics); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// distWidth is the number of characters of a distribution drawn in a
+// row of the comparison.
+const distWidth = 24
+
+// distScale returns the range of the values of old and new, on which
+// both are drawn.
+func distScale(old, new Sample) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range []Sample{old, new} {
+		for _, v := range s.Values {
+			lo = math.Min(lo, v)
+			hi = math.Max(hi, v)
+		}
+	}
+	return lo, hi
+}
+
+// distCell returns the character cell of v in the range [lo, hi].
+func distCell(v, lo, hi float64) int {
+	if hi == lo {
+		return distWidth / 2
+	}
+	i := int((v - lo) / (hi - lo) * distWidth)
+	if i >= distWidth {
+		i = distWidth - 1
+	}
+	return i
+}
+
+// boxPlotText draws the box plot of s in the range [lo, hi]: the
+// whiskers span the values without outliers, the box the quartiles, with
+// the median as ┃, and the outliers are •.
+func boxPlotText(s Sample, lo, hi float64) string {
+	cells := []rune(strings.Repeat(" ", distWidth))
+	if len(s.RValues) == 0 {
+		return string(cells)
+	}
+
+	min, max := distCell(s.Min, lo, hi), distCell(s.Max, lo, hi)
+	for i := min; i <= max; i++ {
+		cells[i] = '─'
+	}
+	cells[min] = '├'
+	cells[max] = '┤'
+	q1 := distCell(Percentile(s.RValues, 0.25), lo, hi)
+	q3 := distCell(Percentile(s.RValues, 0.75), lo, hi)
+	for i := q1; i <= q3; i++ {
+		cells[i] = '█'
+	}
+	cells[distCell(Percentile(s.RValues, 0.5), lo, hi)] = '┃'
+	for _, v := range outliers(s) {
+		cells[distCell(v, lo, hi)] = '•'
+	}
+	return string(cells)
+}
+
+// histogramText draws the histogram of the values of s in the range
+// [lo, hi] as a sparkline, with the bars scaled to top values.
+func histogramText(s Sample, lo, hi float64, top int) string {
+	bins := distBins(s, lo, hi)
+	var b strings.Builder
+	for _, n := range bins {
+		if n == 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		i := (n*len(sparkLevels) - 1) / top
+		if i >= len(sparkLevels) {
+			i = len(sparkLevels) - 1
+		}
+		b.WriteRune(sparkLevels[i])
+	}
+	return b.String()
+}
+
+// distBins counts the values of s in each character cell.
+func distBins(s Sample, lo, hi float64) []int {
+	bins := make([]int, distWidth)
+	for _, v := range s.Values {
+		bins[distCell(v, lo, hi)]++
+	}
+	return bins
+}
+
+// distTexts draws old and new on the same scale, as box plots if dist is
+// "box", otherwise as histograms.
+func distTexts(dist string, old, new Sample) (string, string) {
+	lo, hi := distScale(old, new)
+	if dist == "box" {
+		return boxPlotText(old, lo, hi), boxPlotText(new, lo, hi)
+	}
+
+	top := 1
+	for _, s := range []Sample{old, new} {
+		for _, n := range distBins(s, lo, hi) {
+			if n > top {
+				top = n
+			}
+		}
+	}
+	return histogramText(old, lo, hi, top), histogramText(new, lo, hi, top)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A ComplexityModel is a function of N to which the times of a
+// benchmark family can be fitted.
+type ComplexityModel struct {
+	Name string
+	F    func(n float64) float64
+}
+
+// complexityModels are the models tried by -fit, the same ones google
+// benchmark uses for ->Complexity(benchmark::oAuto).
+var complexityModels = []ComplexityModel{
+	{"O(1)", func(n float64) float64 { return 1 }},
+	{"O(lgN)", func(n float64) float64 { return math.Log2(n) }},
+	{"O(N)", func(n float64) float64 { return n }},
+	{"O(NlgN)", func(n float64) float64 { return n * math.Log2(n) }},
+	{"O(N^2)", func(n float64) float64 { return n * n }},
+	{"O(N^3)", func(n float64) float64 { return n * n * n }},
+}
+
+// parseComplexityModel parses a custom model given as a product of
+// factors N, lgN, N^p or lgN^p, e.g. N^1.5 or N*lgN^2.
+func parseComplexityModel(s string) (ComplexityModel, error) {
+	type factor struct {
+		log bool
+		exp float64
+	}
+	var factors []factor
+	for _, f := range strings.Split(s, "*") {
+		f = strings.TrimSpace(f)
+		exp := 1.0
+		if i := strings.Index(f, "^"); i != -1 {
+			v, err := strconv.ParseFloat(f[i+1:], 64)
+			if err != nil {
+				return ComplexityModel{}, fmt.Errorf("invalid exponent in '%s': %w", s, err)
+			}
+			f, exp = f[:i], v
+		}
+		switch f {
+		case "N":
+			factors = append(factors, factor{exp: exp})
+		case "lgN", "logN":
+			factors = append(factors, factor{log: true, exp: exp})
+		default:
+			return ComplexityModel{}, fmt.Errorf("invalid factor '%s' in '%s', expected N or lgN", f, s)
+		}
+	}
+	return ComplexityModel{
+		Name: "O(" + s + ")",
+		F: func(n float64) float64 {
+			v := 1.0
+			for _, f := range factors {
+				x := n
+				if f.log {
+					x = math.Log2(n)
+				}
+				v *= math.Pow(x, f.exp)
+			}
+			return v
+		},
+	}, nil
+}
+
+// A Fit is the result of fitting the times of a family to a model:
+// time(N) = Coef * Model(N). RMS is the root mean square error
+// normalized by the mean time.
+type Fit struct {
+	Model ComplexityModel
+	Coef  float64
+	RMS   float64
+}
+
+// fitModel fits ts = coef * model(ns) with least squares, like google
+// benchmark's MinimalLeastSq.
+func fitModel(ns, ts []float64, model ComplexityModel) Fit {
+	sumXX, sumXY, sumT := 0.0, 0.0, 0.0
+	for i := range ns {
+		x := model.F(ns[i])
+		sumXX += x * x
+		sumXY += x * ts[i]
+		sumT += ts[i]
+	}
+	fit := Fit{Model: model}
+	if sumXX == 0 {
+		fit.RMS = math.Inf(1)
+		return fit
+	}
+	fit.Coef = sumXY / sumXX
+
+	rms := 0.0
+	for i := range ns {
+		d := ts[i] - fit.Coef*model.F(ns[i])
+		rms += d * d
+	}
+	mean := sumT / float64(len(ts))
+	fit.RMS = math.Sqrt(rms/float64(len(ts))) / mean
+	return fit
+}
+
+// bestFit returns the fit with the smallest RMS.
+func bestFit(ns, ts []float64, models []ComplexityModel) Fit {
+	var best Fit
+	for i, m := range models {
+		f := fitModel(ns, ts, m)
+		if i == 0 || f.RMS < best.RMS {
+			best = f
+		}
+	}
+	return best
+}
+
+// scalingN returns the N of the benchmark in r: its complexity_n if set,
+// otherwise the value of its first argument.
+func scalingN(r row) (float64, bool) {
+	if r.N > 0 {
+		return float64(r.N), true
+	}
+	if len(r.Key.Args) == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(r.Key.Args[0].Value, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// scalingFamily returns the name of the family of r without the
+// argument used as N.
+func scalingFamily(r row) string {
+	k := r.Key
+	if len(k.Args) > 0 {
+		k.Args = k.Args[1:]
+	}
+	return k.String()
+}
+
+// PrintFit fits the times of what, "real" or "cpu", of every family
+// for old and new to models and prints the best fits. It also reports
+// the crossover points: the N between which new goes from being
+// significantly faster than old to being significantly slower, or
+// the reverse.
+func (p Printer) PrintFit(what string, old, new []Metric, models []ComplexityModel) error {
+	if what != "real" && what != "cpu" {
+		return fmt.Errorf("unknown what value '%s'", what)
+	}
+
+	rows, err := matchRows(what, old, new)
+	if err != nil {
+		return err
+	}
+
+	type point struct {
+		n float64
+		r row
+	}
+	var families []string
+	points := make(map[string][]point)
+	for _, r := range rows {
+		n, ok := scalingN(r)
+		if !ok {
+			continue
+		}
+		f := scalingFamily(r)
+		if _, ok := points[f]; !ok {
+			families = append(families, f)
+		}
+		points[f] = append(points[f], point{n, r})
+	}
+
+	title := what + " fit"
+	fmt.Fprintf(p.w, "%s\tnote\told\tnew\n", title)
+	fmt.Fprintf(p.w, "%s\t----\t---\t---\n", strings.Repeat("-", len(title)))
+
+	for _, f := range families {
+		ps := points[f]
+		if len(ps) < 2 {
+			continue
+		}
+		sort.Slice(ps, func(i, j int) bool { return ps[i].n < ps[j].n })
+
+		ns := make([]float64, len(ps))
+		ots := make([]float64, len(ps))
+		nts := make([]float64, len(ps))
+		for i, pt := range ps {
+			ns[i] = pt.n
+			ots[i] = pt.r.Old.Mean
+			nts[i] = pt.r.New.Mean
+		}
+		of := bestFit(ns, ots, models)
+		nf := bestFit(ns, nts, models)
+
+		var notes []string
+		if of.Model.Name != nf.Model.Name {
+			notes = append(notes, "CHANGED")
+		}
+
+		// sign of the significant changes, in order of N
+		lastN, lastSign := 0.0, 0
+		for _, pt := range ps {
+			c := pt.r.Old.Compare(pt.r.New)
+			if !c.Significant || c.Delta == 0 {
+				continue
+			}
+			sign := 1
+			if c.Delta < 0 {
+				sign = -1
+			}
+			if lastSign != 0 && sign != lastSign {
+				small, large := "faster", "slower"
+				if lastSign > 0 {
+					small, large = "slower", "faster"
+				}
+				notes = append(notes, fmt.Sprintf("crossover: new %s for N<=%g, %s for N>=%g",
+					small, lastN, large, pt.n))
+			}
+			lastN, lastSign = pt.n, sign
+		}
+
+		note := "~"
+		if len(notes) > 0 {
+			note = strings.Join(notes, ", ")
+		}
+
+		unit := ps[0].r.Unit
+		fmt.Fprintf(p.w, "%s\t%s\t%.2f%s %s (rms=%.0f%%)\t%.2f%s %s (rms=%.0f%%)\n",
+			f, note,
+			of.Coef, unit, of.Model.Name, of.RMS*100,
+			nf.Coef, unit, nf.Model.Name, nf.RMS*100)
+	}
+
+	return p.w.Flush()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const gitUsageExtra = `
+Checks out the two git revisions(new-rev defaults to HEAD) of the
+repository in the current directory into temporary worktrees, builds
+them, runs their benchmarks like the run command and compares the
+results.
+
+The build and benchmark commands are read from the JSON config file
+given with -config, by default .gbenchdiff.json in the root of the
+repository:
+    {
+        "build": "cmake -B build -DCMAKE_BUILD_TYPE=Release && cmake --build build",
+        "bench": "build/bench",
+        "args": ["--benchmark_filter=BM_foo"]
+    }
+build is run with sh in the root of every worktree, bench is the google
+benchmark executable, relative to the root of the worktree, and args
+are given to it. The worktrees are removed at the end.
+`
+
+func gitUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s git [options] old-rev [new-rev]\n", os.Args[0])
+		fmt.Fprint(os.Stderr, "options:\n")
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, gitUsageExtra)
+		os.Exit(1)
+	}
+}
+
+// A gitConfig tells how to build and run the benchmarks of a revision.
+type gitConfig struct {
+	Build string   `json:"build"`
+	Bench string   `json:"bench"`
+	Args  []string `json:"args"`
+}
+
+func loadGitConfig(path string) (gitConfig, error) {
+	var cfg gitConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.Bench == "" {
+		return cfg, fmt.Errorf("%s: bench is not set", path)
+	}
+	return cfg, nil
+}
+
+// gitCmd implements the git command.
+func gitCmd(args []string) error {
+	var fConfig string
+	var ro runOptions
+	var opts diffOptions
+
+	fs := flag.NewFlagSet("git", flag.ExitOnError)
+	opts.register(fs)
+	ro.register(fs)
+	fs.StringVar(&fConfig, "config", "", "the config file(default .gbenchdiff.json in the root of the repository)")
+	fs.Usage = gitUsage(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	args = fs.Args()
+	if len(args) < 1 || len(args) > 2 {
+		fs.Usage()
+	}
+	revs := [2]string{args[0], "HEAD"}
+	if len(args) == 2 {
+		revs[1] = args[1]
+	}
+
+	if err := ro.check(); err != nil {
+		return err
+	}
+	if err := opts.init(); err != nil {
+		return err
+	}
+
+	top, err := git("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	if fConfig == "" {
+		fConfig = filepath.Join(top, ".gbenchdiff.json")
+	}
+	cfg, err := loadGitConfig(fConfig)
+	if err != nil {
+		return err
+	}
+
+	var commits [2]string
+	for i, rev := range revs {
+		commits[i], err = git(top, "rev-parse", "--verify", rev+"^{commit}")
+		if err != nil {
+			return err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "gbenchdiff-git")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var exes [2]string
+	for i, name := range []string{"old", "new"} {
+		wt := filepath.Join(dir, name)
+		if _, err := git(top, "worktree", "add", "--detach", wt, commits[i]); err != nil {
+			return err
+		}
+		defer git(top, "worktree", "remove", "--force", wt)
+
+		fmt.Fprintf(os.Stderr, "building %s(%s %.12s)\n", name, revs[i], commits[i])
+		if err := build(wt, cfg.Build, ro.verbose); err != nil {
+			return fmt.Errorf("%s: %w", revs[i], err)
+		}
+		exes[i] = filepath.Join(wt, cfg.Bench)
+	}
+
+	oldRes, newRes, err := runBenchmarks(exes[0], exes[1], cfg.Args, &ro, &opts)
+	if err != nil {
+		return err
+	}
+	return diff(oldRes, newRes, &opts)
+}
+
+// git runs git in dir and returns its output without the trailing
+// newline.
+func git(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// build runs the build command with sh in dir. Its output is printed on
+// stderr if verbose is true, otherwise only if it fails.
+func build(dir, command string, verbose bool) error {
+	if command == "" {
+		return nil
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if verbose {
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		io.Copy(os.Stderr, &out)
+		return fmt.Errorf("build failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// goTestFormat is the text output of `go test -bench`.
+type goTestFormat struct{}
+
+// goTestPrefixes are the prefixes of the lines which identify go test
+// output.
+var goTestPrefixes = []string{"goos: ", "goarch: ", "pkg: ", "Benchmark"}
+
+func (goTestFormat) Name() string { return "gotest" }
+
+func (goTestFormat) Detect(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		for _, p := range goTestPrefixes {
+			if strings.HasPrefix(line, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (goTestFormat) Decode(name string, r io.Reader) (Result, error) {
+	return decodeGoTest(r)
+}
+
+// decodeGoTest reads the text output of `go test -bench`.
+//
+// Every benchmark line becomes one repetition(so -count=N gives N
+// repetitions). ns/op is used as real time and all other units(MB/s,
+// B/op, allocs/op, units given to b.ReportMetric) are stored as counters
+// named after the unit. The -GOMAXPROCS suffix of the names becomes the
+// procs modifier, so that it is not mistaken for an argument.
+func decodeGoTest(r io.Reader) (Result, error) {
+	var res Result
+	reps := make(map[string]uint64)
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := s.Text()
+
+		if strings.HasPrefix(text, "pkg: ") {
+			res.Context.Executable = strings.TrimPrefix(text, "pkg: ")
+			continue
+		}
+
+		if !strings.HasPrefix(text, "Benchmark") {
+			continue
+		}
+		f := strings.Fields(text)
+		// a benchmark which logs prints its name on a separate line
+		if len(f) < 4 || len(f)%2 != 0 {
+			continue
+		}
+
+		b, err := goTestBenchmark(f)
+		if err != nil {
+			return Result{}, fmt.Errorf("gotest: line %d: %w", line, err)
+		}
+		b.RepetitionIndex = reps[b.Name]
+		reps[b.Name]++
+		res.Benchmarks = append(res.Benchmarks, b)
+	}
+	if err := s.Err(); err != nil {
+		return Result{}, err
+	}
+
+	procs := goTestProcs(res.Benchmarks)
+	for i := range res.Benchmarks {
+		b := &res.Benchmarks[i]
+		b.Repetitions = reps[b.Name]
+		if procs {
+			j := strings.LastIndex(b.Name, "-")
+			b.RunName = b.Name[:j] + "/procs:" + b.Name[j+1:]
+		}
+	}
+	return res, nil
+}
+
+// goTestProcs reports whether the names of the benchmarks end with the
+// -N which go test adds when GOMAXPROCS is not 1: all of them end with
+// -N and every benchmark ran with the same values of N(one, or the
+// ones given to -cpu). Otherwise the numbers are part of the names, e.g.
+// BenchmarkFoo/n-10 run with GOMAXPROCS=1. A file where all the names end
+// with numbers in the same way, e.g. only BenchmarkFoo/n-10 and
+// BenchmarkFoo/n-20, can't be told apart.
+func goTestProcs(benchmarks []Benchmark) bool {
+	if len(benchmarks) == 0 {
+		return false
+	}
+	procs := make(map[string]map[string]bool)
+	for _, b := range benchmarks {
+		i := strings.LastIndex(b.Name, "-")
+		if i == -1 {
+			return false
+		}
+		if _, err := strconv.Atoi(b.Name[i+1:]); err != nil {
+			return false
+		}
+		base := b.Name[:i]
+		if procs[base] == nil {
+			procs[base] = make(map[string]bool)
+		}
+		procs[base][b.Name[i+1:]] = true
+	}
+	var first map[string]bool
+	for _, p := range procs {
+		if first == nil {
+			first = p
+			continue
+		}
+		if len(p) != len(first) {
+			return false
+		}
+		for n := range p {
+			if !first[n] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func goTestBenchmark(f []string) (Benchmark, error) {
+	b := Benchmark{
+		Name:     f[0],
+		RunName:  f[0],
+		RunType:  "iteration",
+		TimeUnit: "ns",
+		Threads:  1,
+	}
+	iters, err := strconv.ParseUint(f[1], 10, 64)
+	if err != nil {
+		return b, err
+	}
+	b.Iterations = iters
+
+	for i := 2; i < len(f); i += 2 {
+		v, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return b, err
+		}
+		switch unit := f[i+1]; unit {
+		case "ns/op":
+			b.RealTime = v
+		default:
+			if b.Counters == nil {
+				b.Counters = make(map[string]float64)
+			}
+			b.Counters[unit] = v
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeGoTest(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Benchmark
+	}{
+		{
+			name: "count",
+			input: `goos: linux
+goarch: amd64
+pkg: example.com/strs
+cpu: Intel(R) Core(TM) i7-8550U CPU @ 1.80GHz
+BenchmarkJoin-8   	 5243958	       228.1 ns/op	     112 B/op	       1 allocs/op
+BenchmarkJoin-8   	 5301442	       226.4 ns/op	     112 B/op	       1 allocs/op
+BenchmarkCopy/n-1000-8         	  803468	      1482 ns/op	 674.58 MB/s
+BenchmarkLog-8
+    strs_test.go:42: logged
+BenchmarkLog-8   	 1000000	      1051 ns/op	         3.000 widgets/op
+PASS
+ok  	example.com/strs	6.254s
+`,
+			want: []Benchmark{
+				{
+					Name: "BenchmarkJoin-8", RunName: "BenchmarkJoin/procs:8", RunType: "iteration", TimeUnit: "ns",
+					Threads: 1, Iterations: 5243958, RealTime: 228.1, Repetitions: 2, RepetitionIndex: 0,
+					Counters: map[string]float64{"B/op": 112, "allocs/op": 1},
+				},
+				{
+					Name: "BenchmarkJoin-8", RunName: "BenchmarkJoin/procs:8", RunType: "iteration", TimeUnit: "ns",
+					Threads: 1, Iterations: 5301442, RealTime: 226.4, Repetitions: 2, RepetitionIndex: 1,
+					Counters: map[string]float64{"B/op": 112, "allocs/op": 1},
+				},
+				{
+					Name: "BenchmarkCopy/n-1000-8", RunName: "BenchmarkCopy/n-1000/procs:8", RunType: "iteration",
+					TimeUnit: "ns", Threads: 1, Iterations: 803468, RealTime: 1482, Repetitions: 1,
+					Counters: map[string]float64{"MB/s": 674.58},
+				},
+				{
+					Name: "BenchmarkLog-8", RunName: "BenchmarkLog/procs:8", RunType: "iteration", TimeUnit: "ns",
+					Threads: 1, Iterations: 1000000, RealTime: 1051, Repetitions: 1,
+					Counters: map[string]float64{"widgets/op": 3},
+				},
+			},
+		},
+		{
+			// with -cpu=1,4 the names of the runs with GOMAXPROCS=1 have
+			// no suffix, so the suffixes can't be told apart from
+			// arguments and are kept in the names
+			name: "mixed cpu",
+			input: `BenchmarkHash/n-1000   	  120000	     10000 ns/op
+BenchmarkHash/n-1000-4 	  480000	      2500 ns/op
+`,
+			want: []Benchmark{
+				{
+					Name: "BenchmarkHash/n-1000", RunName: "BenchmarkHash/n-1000", RunType: "iteration",
+					TimeUnit: "ns", Threads: 1, Iterations: 120000, RealTime: 10000, Repetitions: 1,
+				},
+				{
+					Name: "BenchmarkHash/n-1000-4", RunName: "BenchmarkHash/n-1000-4", RunType: "iteration",
+					TimeUnit: "ns", Threads: 1, Iterations: 480000, RealTime: 2500, Repetitions: 1,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := decodeGoTest(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.Benchmarks, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", res.Benchmarks, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoTestProcs(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  bool
+	}{
+		{nil, false},
+		{[]string{"BenchmarkA-8", "BenchmarkB/n-10-8"}, true},
+		{[]string{"BenchmarkA-4", "BenchmarkA-8"}, true},
+		{[]string{"BenchmarkA", "BenchmarkA-4"}, false},
+		{[]string{"BenchmarkA-2", "BenchmarkA-4", "BenchmarkB-2", "BenchmarkB-4"}, true},
+		{[]string{"BenchmarkA/n-10", "BenchmarkA/n-10-4"}, false},
+		{[]string{"BenchmarkA/n-10-2", "BenchmarkA/n-20-2", "BenchmarkA/n-20-4"}, false},
+		{[]string{"BenchmarkA/n-1000"}, true},
+		{[]string{"BenchmarkA/n-1000", "BenchmarkB"}, false},
+		{[]string{"BenchmarkA-x"}, false},
+	}
+	for _, tt := range tests {
+		var benchmarks []Benchmark
+		for _, n := range tt.names {
+			benchmarks = append(benchmarks, Benchmark{Name: n})
+		}
+		if got := goTestProcs(benchmarks); got != tt.want {
+			t.Errorf("goTestProcs(%v) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// hyperfineFormat is the file written by hyperfine --export-json.
+//
+// Every command becomes a benchmark named after the command and every
+// run one repetition. The times are converted from seconds to
+// milliseconds.
+type hyperfineFormat struct{}
+
+type hyperfineResult struct {
+	Command string    `json:"command"`
+	Times   []float64 `json:"times"`
+}
+
+func (hyperfineFormat) Name() string { return "hyperfine" }
+
+func (hyperfineFormat) Detect(data []byte) bool {
+	var v struct {
+		Results []map[string]json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil || len(v.Results) == 0 {
+		return false
+	}
+	_, command := v.Results[0]["command"]
+	_, times := v.Results[0]["times"]
+	return command && times
+}
+
+func (hyperfineFormat) Decode(name string, r io.Reader) (Result, error) {
+	var v struct {
+		Results []hyperfineResult `json:"results"`
+	}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return Result{}, err
+	}
+
+	var res Result
+	for _, hr := range v.Results {
+		for i, t := range hr.Times {
+			res.Benchmarks = append(res.Benchmarks, Benchmark{
+				Name:            hr.Command,
+				RunName:         hr.Command,
+				RunType:         "iteration",
+				TimeUnit:        "ms",
+				Threads:         1,
+				Repetitions:     uint64(len(hr.Times)),
+				RepetitionIndex: uint64(i),
+				Iterations:      1,
+				RealTime:        t * 1e3,
+				SingleRun:       true,
+			})
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// An InputFormat decodes the output of a benchmark framework into a
+// Result.
+type InputFormat interface {
+	// Name returns the value given to -format to select this format.
+	Name() string
+
+	// Detect reports whether data looks like this format.
+	Detect(data []byte) bool
+
+	// Decode decodes the input named name(usually a file path).
+	Decode(name string, r io.Reader) (Result, error)
+}
+
+// inputFormats are the supported formats, in the order in which they are
+// tried when the format is detected automatically.
+var inputFormats = []InputFormat{
+	gbenchJSONFormat{},
+	nanobenchFormat{},
+	hyperfineFormat{},
+	criterionFormat{},
+	catch2Format{},
+	goTestFormat{},
+	csvFormat{},
+}
+
+// inputFormatNames returns the names accepted by -format.
+func inputFormatNames() string {
+	names := []string{"auto"}
+	for _, f := range inputFormats {
+		names = append(names, f.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// loadResult reads the benchmark result stored at path, or from stdin if
+// path is "-". Directories are read as Criterion output directories.
+func loadResult(path string, format string) (Result, error) {
+	if path == "-" {
+		return readResult(path, os.Stdin, format)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Result{}, err
+	}
+	if fi.IsDir() {
+		return readCriterionDir(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+
+	return readResult(path, f, format)
+}
+
+// readResult decodes a benchmark result from r in the given format.
+// If format is "auto", the format is detected from the content.
+// Compressed input is decompressed first.
+func readResult(name string, r io.Reader, format string) (Result, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return Result{}, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return Result{}, errors.New("empty input")
+	}
+
+	for _, f := range inputFormats {
+		if format == f.Name() || (format == "auto" && f.Detect(data)) {
+			return f.Decode(name, bytes.NewReader(data))
+		}
+	}
+
+	if format == "auto" {
+		return Result{}, errors.New("cannot detect input format")
+	}
+	return Result{}, fmt.Errorf("unknown input format '%s'", format)
+}
+
+// jsonObject decodes the top level keys of the JSON object in data.
+// It returns nil if data is not a JSON object.
+func jsonObject(data []byte) map[string]json.RawMessage {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// gbenchJSONFormat is the output of google benchmark with
+// --benchmark_out_format=json(the default).
+type gbenchJSONFormat struct{}
+
+func (gbenchJSONFormat) Name() string { return "json" }
+
+func (gbenchJSONFormat) Detect(data []byte) bool {
+	_, ok := jsonObject(data)["benchmarks"]
+	return ok
+}
+
+func (gbenchJSONFormat) Decode(name string, r io.Reader) (Result, error) {
+	var res Result
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
@@ -1,14 +1,11 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"regexp"
 	"runtime/debug"
-	"strings"
-	"text/tabwriter"
 )
 
 const usageExtra = `
@@ -27,10 +24,143 @@ IMPORTANT:
 Run the benchmark with the following flags:
     --benchmark_out=file.json
     --benchmark_repetitions(=10 should be enough in most cases)
+
+Other inputs are also accepted, the format is detected from the file
+contents unless -format is given:
+- csv: google benchmark with --benchmark_out_format=csv
+- gotest: text output of "go test -bench"(use -count for repetitions)
+- catch2: Catch2 with --reporter xml
+- nanobench: nanobench with the templates::json() template
+- criterion: Rust Criterion sample.json or estimates.json, or the
+  whole target/criterion directory
+- hyperfine: hyperfine --export-json, every command is a benchmark
+
+The bytes_per_second and items_per_second of google benchmark are
+compared as user counters(see -with-counters).
+
+For go test output, B/op, allocs/op, MB/s and the units reported with
+b.ReportMetric are compared as user counters(see -with-counters). The
+-N suffix which go test adds to the names when GOMAXPROCS is not 1 is
+the procs modifier(e.g. -param procs=8), it is not a number of threads.
+
+Use - as file name to read from stdin. Files compressed with gzip, bzip2,
+zstd, xz or lz4 are decompressed(zstd, xz and lz4 need the command with
+the same name to be installed).
+
+Benchmark names are split in family, template parameters, arguments and
+modifiers, e.g. BM_foo<int>/8/len:16/real_time/threads:4 has family BM_foo,
+template parameter t0=int, arguments 0=8 and len=16 and modifiers
+real_time and threads=4. Use -family and -param to select benchmarks by
+these values.
+
+With -group, the benchmarks are printed grouped by family. Every family
+starts with a summary line with the geometric mean and the median of the
+% changes in mean of its benchmarks and the number of significant changes.
+
+Benchmarks are matched by name. If a benchmark was renamed, use -rename or
+-rename-file to rewrite the old names, e.g. -rename 'BM_Parse$=>BM_ParseJson'
+or -rename 'BM_(\w+)/len:=>BM_$1/size:'. The replacement can refer to
+the submatches of the regex with $1 or ${name}.
+
+With -complexity, the complexities computed by google benchmark for the
+benchmarks registered with ->Complexity() are compared: the change of
+the coefficient if the complexity class is the same, otherwise CHANGED
+and the old and new classes, e.g. O(N) -> O(NlgN).
+
+With -fit, the times of every family are fitted to the models O(1),
+O(lgN), O(N), O(NlgN), O(N^2), O(N^3) and the one given with -fit-custom,
+even for benchmarks without ->Complexity(). N is the complexity N, if
+set, otherwise the first argument. The best fits of old and new are
+printed, with CHANGED if they differ, and the crossover points where new
+goes from faster than old for small N to slower for large N, or reverse.
+
+With -threads, the benchmarks run with several thread counts(e.g.
+->Threads(1)->Threads(8)) are compared by family: time, throughput and
+speedup and efficiency relative to the smallest thread count. A SCALING
+REGRESSION is reported when new is significantly slower and its
+efficiency dropped by more than -threads-threshold. Only the real time is
+used, even with -with-cpu: the CPU time is summed over the threads, so it
+doesn't drop when more threads share the work.
+
+The repetitions which failed(state.SkipWithError()) or were skipped
+(state.SkipWithMessage()) are not used in the comparison. The benchmarks
+with such repetitions are listed separately, with NEW FAILURE if they
+fail only in the new file and FIXED if they fail only in the old one.
+
+The contexts of the two files are compared before the benchmarks. Every
+context field has a level: error(the comparison fails), warn(the
+difference is printed) or ignore. The defaults are:
+- error: num_cpus, mhz_per_cpu, cpu_scaling_enabled, caches
+- warn: library_build_type, library_version, custom context
+  (--benchmark_context)
+- ignore: date, host_name, executable, load_avg, json_schema_version
+Change them with -ctx, e.g. -ctx mhz_per_cpu=warn -ctx custom=ignore
+(custom sets the level of all the custom context keys, which can also be
+set one by one). Use -ctx-diff to print all the fields side by side.
+
+Before the comparison, warnings are printed for the environments which
+make the results unreliable: the benchmark library built in debug mode,
+CPU frequency scaling enabled, a load average per CPU above -max-load
+and different CPU frequencies. With -strict, they fail the comparison.
+
+The benchmarks which ran with a different number of repetitions, with
+less than -min-reps repetitions, with less than -min-iters iterations or
+with very different numbers of iterations(e.g. different
+--benchmark_min_time) are listed as diagnostics, their p-values can be
+misleading.
+
+Use "run" to run two google benchmark executables alternately instead of
+comparing existing results, see "run -h".
+
+Use "git" to build and run the benchmarks of two git revisions, see
+"git -h".
+
+Use "store" to keep the results in a local store and compare them by
+revision or tag, see "store -h".
+
+Use "trend" to follow the benchmarks over a series of results, see
+"trend -h".
+
+Use "serve" to explore the comparisons in a browser, see "serve -h".
+
+With -plots dir, the distributions of the repetitions of every benchmark
+are drawn as a box plot, a strip plot and a histogram, old and new on the
+same scale and the outliers marked, in a standalone SVG file per
+benchmark, with an index.html linking them.
+
+With -dist box or -dist hist, every row also shows the distributions of
+the old and new repetitions on the same scale, as box plots(├ ┤ the
+range without outliers, █ the quartiles, ┃ the median, • the outliers)
+or histograms.
+
+On a terminal, the significant changes are colored: red if slower,
+green if faster, yellow for user counters. Use -color never or set
+NO_COLOR to disable it, -color always to force it. Use -sort to order
+the rows by delta(largest increase first), p, name or time(largest new
+time first) and -significant to print only the significant changes.
 `
 
 func main() {
-	if err := run(); err != nil {
+	var err error
+	cmd := ""
+	if len(os.Args) > 1 {
+		cmd = os.Args[1]
+	}
+	switch cmd {
+	case "run":
+		err = runCmd(os.Args[2:])
+	case "git":
+		err = gitCmd(os.Args[2:])
+	case "store":
+		err = storeCmd(os.Args[2:])
+	case "trend":
+		err = trendCmd(os.Args[2:])
+	case "serve":
+		err = serveCmd(os.Args[2:])
+	default:
+		err = run()
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
@@ -38,6 +168,11 @@ func main() {
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s [options] old.json new.json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s run [options] old-bench new-bench [-- bench args]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s git [options] old-rev [new-rev]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s store add|list|query|prune|diff [options] [args]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s trend [options] [file...]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s serve [options] file...\n", os.Args[0])
 	fmt.Fprint(os.Stderr, "options:\n")
 	flag.PrintDefaults()
 	fmt.Fprint(os.Stderr, usageExtra)
@@ -46,15 +181,13 @@ func usage() {
 
 func run() error {
 	// var fHtml bool
-	var fNoCtxCheck bool
-	var fWithCPUTime bool
-	var fFilter string
+	var fFormat string
 	var fVersion bool
+	var opts diffOptions
 
 	// flag.BoolVar(&fHtml, "html", false, "print result as HTML")
-	flag.BoolVar(&fNoCtxCheck, "no-ctx", false, "don't compare benchmark contexts")
-	flag.BoolVar(&fWithCPUTime, "with-cpu", false, "compare also CPU time")
-	flag.StringVar(&fFilter, "filter", "", "select only the benchmarks with names that match the given regex")
+	opts.register(flag.CommandLine)
+	flag.StringVar(&fFormat, "format", "auto", "input format, one of: "+inputFormatNames())
 	flag.BoolVar(&fVersion, "version", false, "print version")
 
 	flag.Usage = usage
@@ -87,101 +220,25 @@ func run() error {
 		usage()
 	}
 
-	var filterRe *regexp.Regexp
-	if fFilter != "" {
-		re, err := regexp.Compile(fFilter)
-		if err != nil {
-			return err
-		}
-		filterRe = re
+	if err := opts.init(); err != nil {
+		return err
 	}
 
 	oldFilepath := args[0]
 	newFilepath := args[1]
-
-	oldFile, err := os.Open(oldFilepath)
-	if err != nil {
-		return err
+	if oldFilepath == "-" && newFilepath == "-" {
+		return errors.New("only one of the files can be read from stdin")
 	}
-	defer oldFile.Close()
 
-	newFile, err := os.Open(newFilepath)
+	oldRes, err := loadResult(oldFilepath, fFormat)
 	if err != nil {
-		return err
-	}
-	defer newFile.Close()
-
-	var oldRes Result
-	if err := json.NewDecoder(oldFile).Decode(&oldRes); err != nil {
-		return err
-	}
-
-	var newRes Result
-	if err := json.NewDecoder(newFile).Decode(&newRes); err != nil {
-		return err
-	}
-
-	if !fNoCtxCheck {
-		if err := oldRes.Context.Equals(newRes.Context); err != nil {
-			return fmt.Errorf("context check failed: %w", err)
-		}
-	}
-
-	oldMetrics := GetMetrics(oldRes.Benchmarks, filterRe)
-	newMetrics := GetMetrics(newRes.Benchmarks, filterRe)
-
-	printer := Printer{
-		w: tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0),
+		return fmt.Errorf("%s: %w", oldFilepath, err)
 	}
 
-	if err := printer.Print("real", oldMetrics, newMetrics); err != nil {
-		return err
-	}
-
-	if !fWithCPUTime {
-		return nil
-	}
-
-	fmt.Fprintln(printer.w)
-
-	return printer.Print("cpu", oldMetrics, newMetrics)
-}
-
-type Printer struct {
-	w *tabwriter.Writer
-}
-
-func (p Printer) Print(what string, old, new []Metric) error {
-	if what != "real" && what != "cpu" {
-		return fmt.Errorf("unknown what value '%s'", what)
-	}
-
-	fmt.Fprintf(p.w, "%s time\tdelta\tnote\told\tnew\n", what)
-	fmt.Fprintf(p.w, "%s-----\t-----\t----\t---\t---\n", strings.Repeat("-", len(what)))
-
-	for _, o := range old {
-		i := findMetric(new, o.Name)
-		if i == -1 {
-			continue
-		}
-		n := new[i]
-
-		if n.TimeUnit != o.TimeUnit {
-			return fmt.Errorf(
-				"benchmarks have different time units: old=%s, new=%s",
-				o.TimeUnit, n.TimeUnit)
-		}
-
-		fmt.Fprintf(p.w, "%s", n.Name)
-
-		if what == "real" {
-			o.RealTime.Print(p.w, n.RealTime, n.TimeUnit)
-		} else {
-			o.CPUTime.Print(p.w, n.CPUTime, n.TimeUnit)
-		}
-
-		fmt.Fprintln(p.w)
+	newRes, err := loadResult(newFilepath, fFormat)
+	if err != nil {
+		return fmt.Errorf("%s: %w", newFilepath, err)
 	}
 
-	return p.w.Flush()
+	return diff(oldRes, newRes, &opts)
 }
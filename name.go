@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A BenchName is a benchmark name split in its components.
+//
+// google benchmark builds the name as
+// family<template params>/args.../modifiers..., e.g.
+// BM_foo<int>/8/min_time:0.100/real_time/threads:4.
+type BenchName struct {
+	Family    string
+	Template  []string
+	Args      []BenchParam
+	Modifiers []BenchParam
+}
+
+// A BenchParam is one component of a benchmark name. Arguments without
+// a name(e.g. 8 instead of len:8) have an empty Name.
+type BenchParam struct {
+	Name  string
+	Value string
+}
+
+func (p BenchParam) String() string {
+	if p.Name == "" {
+		return p.Value
+	}
+	if p.Value == "" {
+		return p.Name
+	}
+	return p.Name + ":" + p.Value
+}
+
+// benchModifiers are the name components added by google benchmark for
+// the options of a benchmark, as opposed to its arguments, and procs,
+// the GOMAXPROCS of go test benchmarks.
+var benchModifiers = map[string]bool{
+	"min_time":        true,
+	"min_warmup_time": true,
+	"iterations":      true,
+	"repeats":         true,
+	"real_time":       true,
+	"manual_time":     true,
+	"process_time":    true,
+	"threads":         true,
+	"procs":           true,
+}
+
+// ParseBenchName parses the name of a benchmark. threads is the
+// value of the threads field, used when the name has no threads
+// modifier. The family_index and per_family_instance_index of the JSON
+// output are not used: they change when benchmarks are added or removed,
+// so they don't match the same benchmark in old and new.
+func ParseBenchName(name string, threads int) BenchName {
+	var n BenchName
+
+	parts := splitBenchName(name)
+	n.Family, n.Template = splitTemplate(parts[0])
+
+	for _, p := range parts[1:] {
+		var bp BenchParam
+		if i := strings.Index(p, ":"); i != -1 {
+			bp = BenchParam{Name: p[:i], Value: p[i+1:]}
+		} else if benchModifiers[p] {
+			bp = BenchParam{Name: p}
+		} else {
+			bp = BenchParam{Value: p}
+		}
+		if benchModifiers[bp.Name] {
+			n.Modifiers = append(n.Modifiers, bp)
+		} else {
+			n.Args = append(n.Args, bp)
+		}
+	}
+
+	if _, ok := n.Param("threads"); !ok && threads > 1 {
+		n.Modifiers = append(n.Modifiers, BenchParam{Name: "threads", Value: strconv.Itoa(threads)})
+	}
+	sort.SliceStable(n.Modifiers, func(i, j int) bool {
+		return n.Modifiers[i].Name < n.Modifiers[j].Name
+	})
+
+	return n
+}
+
+// splitBenchName splits name at the slashes which are not part of a
+// template parameter list.
+func splitBenchName(name string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range name {
+		switch c {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case '/':
+			if depth == 0 {
+				parts = append(parts, name[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, name[start:])
+}
+
+// splitTemplate splits BM_foo<int, 8> in BM_foo and [int 8].
+func splitTemplate(s string) (string, []string) {
+	i := strings.Index(s, "<")
+	if i == -1 || !strings.HasSuffix(s, ">") {
+		return s, nil
+	}
+	var params []string
+	depth, start := 0, i+1
+	for j := i + 1; j < len(s)-1; j++ {
+		switch s[j] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, strings.TrimSpace(s[start:j]))
+				start = j + 1
+			}
+		}
+	}
+	params = append(params, strings.TrimSpace(s[start:len(s)-1]))
+	return s[:i], params
+}
+
+// FamilyName returns the family with its template parameters, e.g.
+// BM_foo<int>.
+func (n BenchName) FamilyName() string {
+	if len(n.Template) == 0 {
+		return n.Family
+	}
+	return n.Family + "<" + strings.Join(n.Template, ", ") + ">"
+}
+
+// String returns the canonical form of the name, used to match old and
+// new benchmarks.
+func (n BenchName) String() string {
+	var sb strings.Builder
+	sb.WriteString(n.FamilyName())
+	for _, p := range n.Args {
+		sb.WriteString("/" + p.String())
+	}
+	for _, p := range n.Modifiers {
+		sb.WriteString("/" + p.String())
+	}
+	return sb.String()
+}
+
+// Param returns the value of the parameter called name: a named
+// argument or a modifier, the unnamed arguments by their position
+// (0, 1, ...) and the template parameters as t0, t1, ...
+func (n BenchName) Param(name string) (string, bool) {
+	for i, p := range n.Args {
+		if p.Name == name || (p.Name == "" && strconv.Itoa(i) == name) {
+			return p.Value, true
+		}
+	}
+	for _, p := range n.Modifiers {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	for i, t := range n.Template {
+		if fmt.Sprintf("t%d", i) == name {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// A Filter selects benchmarks by their full name, by their family or by
+// the values of their parameters.
+type Filter struct {
+	Name   *regexp.Regexp
+	Family *regexp.Regexp
+	Params map[string]string
+}
+
+// Match reports whether the benchmark called name, with the parsed name
+// n, is selected by f.
+func (f Filter) Match(name string, n BenchName) bool {
+	if f.Name != nil && !f.Name.MatchString(name) {
+		return false
+	}
+	if f.Family != nil && !f.Family.MatchString(n.Family) {
+		return false
+	}
+	for k, v := range f.Params {
+		if pv, ok := n.Param(k); !ok || pv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// paramsFlag is a flag.Value which collects name=value pairs.
+type paramsFlag map[string]string
+
+func (p paramsFlag) String() string {
+	var s []string
+	for k, v := range p {
+		s = append(s, k+"="+v)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (p paramsFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("expected name=value, got '%s'", s)
+	}
+	p[s[:i]] = s[i+1:]
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBenchName(t *testing.T) {
+	tests := []struct {
+		name    string
+		threads int
+		want    BenchName
+		str     string
+	}{
+		{
+			name:    "BM_noop",
+			threads: 1,
+			want:    BenchName{Family: "BM_noop"},
+			str:     "BM_noop",
+		},
+		{
+			name:    "BM_foo<int>/8/min_time:0.100/real_time/threads:4",
+			threads: 4,
+			want: BenchName{
+				Family:   "BM_foo",
+				Template: []string{"int"},
+				Args:     []BenchParam{{Value: "8"}},
+				Modifiers: []BenchParam{
+					{Name: "min_time", Value: "0.100"},
+					{Name: "real_time"},
+					{Name: "threads", Value: "4"},
+				},
+			},
+			str: "BM_foo<int>/8/min_time:0.100/real_time/threads:4",
+		},
+		{
+			name:    "BM_map<std::map<int, std::vector<int>>, 8>/len:16/64",
+			threads: 1,
+			want: BenchName{
+				Family:   "BM_map",
+				Template: []string{"std::map<int, std::vector<int>>", "8"},
+				Args:     []BenchParam{{Name: "len", Value: "16"}, {Value: "64"}},
+			},
+			str: "BM_map<std::map<int, std::vector<int>>, 8>/len:16/64",
+		},
+		{
+			// the threads are taken from the field when the name has
+			// no threads modifier, e.g. with ->ThreadRange()
+			name:    "BM_push/repeats:3/process_time",
+			threads: 8,
+			want: BenchName{
+				Family: "BM_push",
+				Modifiers: []BenchParam{
+					{Name: "process_time"},
+					{Name: "repeats", Value: "3"},
+					{Name: "threads", Value: "8"},
+				},
+			},
+			str: "BM_push/process_time/repeats:3/threads:8",
+		},
+		{
+			// a template path as argument must not be split
+			name:    "BM_parse<Json</*slash*/>>/1",
+			threads: 1,
+			want: BenchName{
+				Family:   "BM_parse",
+				Template: []string{"Json</*slash*/>"},
+				Args:     []BenchParam{{Value: "1"}},
+			},
+			str: "BM_parse<Json</*slash*/>>/1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseBenchName(tt.name, tt.threads)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if s := got.String(); s != tt.str {
+				t.Errorf("String() = %q, want %q", s, tt.str)
+			}
+		})
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	tests := []struct {
+		in     string
+		family string
+		params []string
+	}{
+		{"BM_foo", "BM_foo", nil},
+		{"BM_foo<int>", "BM_foo", []string{"int"}},
+		{"BM_foo<int,8>", "BM_foo", []string{"int", "8"}},
+		{"BM_foo< int , 8 >", "BM_foo", []string{"int", "8"}},
+		{"BM_foo<std::pair<int, float>, std::less<>>", "BM_foo", []string{"std::pair<int, float>", "std::less<>"}},
+		{"BM_foo<int", "BM_foo<int", nil},
+	}
+	for _, tt := range tests {
+		family, params := splitTemplate(tt.in)
+		if family != tt.family || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("splitTemplate(%q) = %q, %q, want %q, %q", tt.in, family, params, tt.family, tt.params)
+		}
+	}
+}
+
+func TestBenchNameParam(t *testing.T) {
+	n := ParseBenchName("BM_foo<int, 8>/16/len:32/real_time/threads:2", 2)
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"0", "16", true},
+		{"len", "32", true},
+		{"1", "", false},
+		{"threads", "2", true},
+		{"real_time", "", true},
+		{"t0", "int", true},
+		{"t1", "8", true},
+		{"t2", "", false},
+		{"min_time", "", false},
+	}
+	for _, tt := range tests {
+		v, ok := n.Param(tt.name)
+		if v != tt.value || ok != tt.ok {
+			t.Errorf("Param(%q) = %q, %v, want %q, %v", tt.name, v, ok, tt.value, tt.ok)
+		}
+	}
+}
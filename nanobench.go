@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// nanobenchFormat is the output of nanobench rendered with the
+// ankerl::nanobench::templates::json() template.
+//
+// Every epoch(measurement) becomes one repetition.
+type nanobenchFormat struct{}
+
+type nanobenchResult struct {
+	Name         string `json:"name"`
+	Measurements []struct {
+		Iterations   uint64  `json:"iterations"`
+		Elapsed      float64 `json:"elapsed"`
+		CPUCycles    float64 `json:"cpucycles"`
+		Instructions float64 `json:"instructions"`
+		BranchMisses float64 `json:"branchmisses"`
+	} `json:"measurements"`
+	Batch float64 `json:"batch"`
+}
+
+func (nanobenchFormat) Name() string { return "nanobench" }
+
+func (nanobenchFormat) Detect(data []byte) bool {
+	var v struct {
+		Results []map[string]json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil || len(v.Results) == 0 {
+		return false
+	}
+	_, ok := v.Results[0]["measurements"]
+	return ok
+}
+
+func (nanobenchFormat) Decode(name string, r io.Reader) (Result, error) {
+	var v struct {
+		Results []nanobenchResult `json:"results"`
+	}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return Result{}, err
+	}
+
+	var res Result
+	for _, nr := range v.Results {
+		batch := nr.Batch
+		if batch <= 0 {
+			batch = 1
+		}
+		for i, m := range nr.Measurements {
+			b := Benchmark{
+				Name:            nr.Name,
+				RunName:         nr.Name,
+				RunType:         "iteration",
+				TimeUnit:        "ns",
+				Threads:         1,
+				Repetitions:     uint64(len(nr.Measurements)),
+				RepetitionIndex: uint64(i),
+				Iterations:      m.Iterations,
+				// elapsed is the time of one iteration, in seconds
+				RealTime: m.Elapsed / batch * 1e9,
+			}
+			counters := map[string]float64{
+				"cpucycles":    m.CPUCycles / batch,
+				"instructions": m.Instructions / batch,
+				"branchmisses": m.BranchMisses / batch,
+			}
+			for k, c := range counters {
+				// zero when the performance counters are not available
+				if c == 0 {
+					continue
+				}
+				if b.Counters == nil {
+					b.Counters = make(map[string]float64)
+				}
+				b.Counters[k] = c
+			}
+			res.Benchmarks = append(res.Benchmarks, b)
+		}
+	}
+	return res, nil
+}
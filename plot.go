@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The layout of the plots: every plot has three panels, box plot, strip
+// plot and histogram, with the old sample above the new one.
+const (
+	plotWidth    = 640
+	plotLeft     = 50
+	plotRight    = 20
+	panelH       = 110
+	histBins     = 20
+	oldColor     = "#1f77b4"
+	newColor     = "#ff7f0e"
+	outlierColor = "#d62728"
+)
+
+// plotScale maps the values of a plot to x coordinates.
+type plotScale struct {
+	lo, hi float64
+}
+
+func newPlotScale(samples ...Sample) plotScale {
+	s := plotScale{lo: math.Inf(1), hi: math.Inf(-1)}
+	for _, sample := range samples {
+		for _, v := range sample.Values {
+			s.lo = math.Min(s.lo, v)
+			s.hi = math.Max(s.hi, v)
+		}
+	}
+	if s.lo == s.hi {
+		s.lo--
+		s.hi++
+	}
+	return s
+}
+
+func (s plotScale) x(v float64) float64 {
+	return plotLeft + (v-s.lo)/(s.hi-s.lo)*(plotWidth-plotLeft-plotRight)
+}
+
+// outliers returns the values of s which are not in s.RValues. Both are
+// sorted.
+func outliers(s Sample) []float64 {
+	var out []float64
+	j := 0
+	for _, v := range s.Values {
+		if j < len(s.RValues) && s.RValues[j] == v {
+			j++
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+type svgWriter struct {
+	strings.Builder
+}
+
+func (w *svgWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(w, format, args...)
+	w.WriteByte('\n')
+}
+
+func (w *svgWriter) text(x, y float64, anchor, s string) {
+	w.printf(`<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x, y, anchor, html.EscapeString(s))
+}
+
+// axis draws the ticks of the scale below a panel starting at y.
+func (w *svgWriter) axis(s plotScale, y float64, unit string) {
+	w.printf(`<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" stroke="#888"/>`, plotLeft, plotWidth-plotRight, y, y)
+	for i := 0; i <= 4; i++ {
+		v := s.lo + (s.hi-s.lo)*float64(i)/4
+		w.printf(`<line x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f" stroke="#888"/>`, s.x(v), s.x(v), y, y+4)
+		w.text(s.x(v), y+16, "middle", fmt.Sprintf("%.2f%s", v, unit))
+	}
+}
+
+// boxPlot draws the box plot of s at y: the whiskers span the values
+// without outliers, the box the quartiles, with the median as a line and
+// the mean as a diamond. The outliers are circles.
+func (w *svgWriter) boxPlot(s plotScale, sample Sample, y float64, color string) {
+	if len(sample.RValues) == 0 {
+		return
+	}
+	q1 := Percentile(sample.RValues, 0.25)
+	q2 := Percentile(sample.RValues, 0.5)
+	q3 := Percentile(sample.RValues, 0.75)
+	w.printf(`<line x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f" stroke="%s"/>`, s.x(sample.Min), s.x(sample.Max), y, y, color)
+	for _, v := range []float64{sample.Min, sample.Max} {
+		w.printf(`<line x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f" stroke="%s"/>`, s.x(v), s.x(v), y-6, y+6, color)
+	}
+	w.printf(`<rect x="%.1f" y="%.1f" width="%.1f" height="20" fill="%s" fill-opacity="0.3" stroke="%s"/>`,
+		s.x(q1), y-10, s.x(q3)-s.x(q1), color, color)
+	w.printf(`<line x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f" stroke="%s" stroke-width="2"/>`, s.x(q2), s.x(q2), y-10, y+10, color)
+	w.printf(`<path d="M%.1f %.1fl4 4l-4 4l-4 -4z" fill="%s"/>`, s.x(sample.Mean), y-4, color)
+	for _, v := range outliers(sample) {
+		w.printf(`<circle cx="%.1f" cy="%.1f" r="3.5" fill="none" stroke="%s"/>`, s.x(v), y, outlierColor)
+	}
+}
+
+// stripPlot draws every value of s at y, spread vertically to reduce
+// the overlap. The outliers are hollow.
+func (w *svgWriter) stripPlot(s plotScale, sample Sample, y float64, color string) {
+	for i, v := range sample.RValues {
+		w.printf(`<circle cx="%.1f" cy="%.1f" r="3" fill="%s" fill-opacity="0.6"/>`, s.x(v), y+float64(i%5-2)*3, color)
+	}
+	for _, v := range outliers(sample) {
+		w.printf(`<circle cx="%.1f" cy="%.1f" r="3.5" fill="none" stroke="%s"/>`, s.x(v), y, outlierColor)
+	}
+}
+
+// histogram draws the histograms of old and new, overlapped, in the
+// panel starting at y.
+func (w *svgWriter) histogram(s plotScale, old, new Sample, y float64) {
+	count := func(sample Sample) []int {
+		bins := make([]int, histBins)
+		for _, v := range sample.Values {
+			i := int((v - s.lo) / (s.hi - s.lo) * histBins)
+			if i >= histBins {
+				i = histBins - 1
+			}
+			bins[i]++
+		}
+		return bins
+	}
+	oldBins, newBins := count(old), count(new)
+	top := 1
+	for i := range oldBins {
+		if oldBins[i] > top {
+			top = oldBins[i]
+		}
+		if newBins[i] > top {
+			top = newBins[i]
+		}
+	}
+
+	bw := (s.x(s.hi) - s.x(s.lo)) / histBins
+	h := float64(panelH - 30)
+	for _, b := range []struct {
+		bins  []int
+		color string
+	}{{oldBins, oldColor}, {newBins, newColor}} {
+		for i, n := range b.bins {
+			if n == 0 {
+				continue
+			}
+			bh := h * float64(n) / float64(top)
+			w.printf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" fill-opacity="0.5"/>`,
+				s.x(s.lo)+float64(i)*bw, y+h-bh, bw, bh, b.color)
+		}
+	}
+	w.text(plotLeft-4, y+8, "end", fmt.Sprint(top))
+}
+
+// plotSVG returns the standalone SVG with the box plot, strip plot and
+// histogram of the old and new samples of r.
+func plotSVG(r row) string {
+	s := newPlotScale(r.Old, r.New)
+	height := 30 + 3*(panelH+20)
+
+	var w svgWriter
+	w.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`, plotWidth, height)
+	w.printf(`<rect width="100%%" height="100%%" fill="white"/>`)
+	w.text(plotLeft, 16, "start", r.Name)
+	w.printf(`<text x="%d" y="16" text-anchor="end"><tspan fill="%s">&#9632; old</tspan> <tspan fill="%s">&#9632; new</tspan> <tspan fill="%s">&#9675; outlier</tspan></text>`,
+		plotWidth-plotRight, oldColor, newColor, outlierColor)
+
+	y := 30.0
+	for _, panel := range []string{"box plot", "strip plot"} {
+		w.text(plotLeft, y+10, "start", panel)
+		for i, p := range []struct {
+			name   string
+			sample Sample
+			color  string
+		}{{"old", r.Old, oldColor}, {"new", r.New, newColor}} {
+			py := y + 35 + float64(i)*40
+			w.text(plotLeft-8, py+4, "end", p.name)
+			if panel == "box plot" {
+				w.boxPlot(s, p.sample, py, p.color)
+			} else {
+				w.stripPlot(s, p.sample, py, p.color)
+			}
+		}
+		w.axis(s, y+panelH-10, r.Unit)
+		y += panelH + 20
+	}
+
+	w.text(plotLeft, y+10, "start", "histogram")
+	w.histogram(s, r.Old, r.New, y+20)
+	w.axis(s, y+panelH-10, r.Unit)
+
+	w.printf(`</svg>`)
+	return w.String()
+}
+
+// plotFileName returns a file name for the ith plot of what for
+// benchmark name.
+func plotFileName(what string, i int, name string) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+				return r
+			}
+			return '_'
+		}, s)
+	}
+	return fmt.Sprintf("%s_%03d_%s.svg", clean(what), i, clean(name))
+}
+
+var plotIndex = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>gbenchdiff plots</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+td { padding: 2px 12px; }
+img { display: block; margin-bottom: 24px; }
+</style>
+</head>
+<body>
+{{range .}}
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>benchmark</th><th>delta</th><th>note</th><th>old</th><th>new</th></tr>
+{{range .Plots}}<tr><td><a href="#{{.File}}">{{.Name}}</a></td><td>{{.Delta}}</td><td>{{.Note}}</td><td>{{.Old}}</td><td>{{.New}}</td></tr>
+{{end}}</table>
+{{range .Plots}}<img id="{{.File}}" src="{{.File}}" alt="{{.Name}}">
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// plotSection is a comparison in the index page.
+type plotSection struct {
+	Title string
+	Plots []plotEntry
+}
+
+type plotEntry struct {
+	File  string
+	Name  string
+	Delta string
+	Note  string
+	Old   string
+	New   string
+}
+
+// WritePlots writes to dir the plots of the old and new samples of every
+// benchmark, for each of whats("real", "cpu" or a counter name), and an
+// index.html with the comparisons and links to the plots.
+func WritePlots(dir string, whats []string, old, new []Metric) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var sections []plotSection
+	for _, what := range whats {
+		rows, err := matchRows(what, old, new)
+		if err != nil {
+			return err
+		}
+
+		section := plotSection{Title: what}
+		if what == "real" || what == "cpu" {
+			section.Title += " time"
+		}
+		for i, r := range rows {
+			e := plotEntry{
+				File: plotFileName(what, i, r.Name),
+				Name: r.Name,
+				Old:  fmt.Sprintf("%.2f%s", r.Old.Mean, r.Unit),
+				New:  fmt.Sprintf("%.2f%s", r.New.Mean, r.Unit),
+			}
+			c := r.Old.Compare(r.New)
+			e.Delta = c.FormatDelta()
+			e.Note = c.Note(len(r.Old.RValues), len(r.New.RValues))
+
+			if err := os.WriteFile(filepath.Join(dir, e.File), []byte(plotSVG(r)), 0o644); err != nil {
+				return err
+			}
+			section.Plots = append(section.Plots, e)
+		}
+		sections = append(sections, section)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	if err := plotIndex.Execute(f, sections); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
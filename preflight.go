@@ -0,0 +1,30 @@
+package main
+
+import "fmt"
+
+// preflight returns warnings about the environments in which the old and
+// new benchmarks ran, which make the comparison unreliable: debug
+// builds of the library, CPU frequency scaling, a load average higher
+// than maxLoad times the number of CPUs and different CPU frequencies.
+func preflight(old, new Context, maxLoad float64) []string {
+	var warnings []string
+	for _, f := range []struct {
+		name string
+		ctx  Context
+	}{{"old", old}, {"new", new}} {
+		c := f.ctx
+		if c.LibraryBuildType == "debug" {
+			warnings = append(warnings, fmt.Sprintf("%s: the benchmark library was built in debug mode, timings will be affected", f.name))
+		}
+		if c.CPUScalingEnabled {
+			warnings = append(warnings, fmt.Sprintf("%s: CPU frequency scaling is enabled, timings will be noisy", f.name))
+		}
+		if len(c.LoadAvg) > 0 && c.NumCPUs > 0 && c.LoadAvg[0] > maxLoad*float64(c.NumCPUs) {
+			warnings = append(warnings, fmt.Sprintf("%s: the load average was %.2f for %d CPUs, timings will be noisy", f.name, c.LoadAvg[0], c.NumCPUs))
+		}
+	}
+	if old.MHzPerCPU != new.MHzPerCPU {
+		warnings = append(warnings, fmt.Sprintf("the CPU frequencies differ: %d MHz vs %d MHz", old.MHzPerCPU, new.MHzPerCPU))
+	}
+	return warnings
+}
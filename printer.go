@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type Printer struct {
+	w *tabwriter.Writer
+
+	// Group prints the benchmarks grouped by family, with a summary
+	// for each family.
+	Group bool
+	// Collapse prints only the summary of the families without
+	// significant changes.
+	Collapse bool
+	// Dist draws the distributions of the old and new samples of every
+	// row on the same scale: "box" as box plots, "hist" as histograms.
+	Dist string
+	// Color colors the changes and the failures.
+	Color bool
+	// Sort is the order of the rows: "delta", "p", "name", "time" or
+	// "" for the order of the old file.
+	Sort string
+	// OnlySignificant prints only the rows with significant changes.
+	OnlySignificant bool
+}
+
+// A row is an old and a new sample of the same benchmark.
+type row struct {
+	Name   string
+	Family string
+	Unit   string
+	Key    BenchName
+	N      int64
+	Old    Sample
+	New    Sample
+}
+
+// Print compares the old and new metrics for what, which is "real",
+// "cpu" or the name of a user counter.
+func (p Printer) Print(what string, old, new []Metric) error {
+	title := what
+	if what == "real" || what == "cpu" {
+		title += " time"
+	}
+
+	rows, err := matchRows(what, old, new)
+	if err != nil {
+		return err
+	}
+
+	sortRows(rows, p.Sort)
+
+	fmt.Fprintf(p.w, "%s\t%s\tnote\told\tnew", title, p.color(colorNone, "delta"))
+	if p.Dist != "" {
+		fmt.Fprintf(p.w, "\told %s\tnew %s", p.Dist, p.Dist)
+	}
+	fmt.Fprintf(p.w, "\n%s\t%s\t----\t---\t---", strings.Repeat("-", len(title)), p.color(colorNone, "-----"))
+	if p.Dist != "" {
+		fmt.Fprintf(p.w, "\t%s\t%s", strings.Repeat("-", len(p.Dist)+4), strings.Repeat("-", len(p.Dist)+4))
+	}
+	fmt.Fprintln(p.w)
+
+	if !p.Group {
+		for _, r := range rows {
+			if p.OnlySignificant && !r.Old.Compare(r.New).Significant {
+				continue
+			}
+			p.printRow(r, "")
+		}
+		return p.w.Flush()
+	}
+
+	for _, g := range groupRows(rows) {
+		p.printFamily(g)
+	}
+
+	return p.w.Flush()
+}
+
+// matchRows pairs the old and new samples of what for the benchmarks
+// found in both old and new.
+func matchRows(what string, old, new []Metric) ([]row, error) {
+	var rows []row
+	index := metricIndex(new)
+	for _, o := range old {
+		i, ok := index[o.KeyString]
+		if !ok {
+			continue
+		}
+		n := new[i]
+
+		// the failures are reported by PrintFailures
+		if !o.Ok() || !n.Ok() {
+			continue
+		}
+
+		if n.TimeUnit != o.TimeUnit {
+			return nil, fmt.Errorf(
+				"benchmarks have different time units: old=%s, new=%s",
+				o.TimeUnit, n.TimeUnit)
+		}
+
+		r := row{
+			Name:   n.Name,
+			Family: n.Key.FamilyName(),
+			Unit:   n.TimeUnit,
+			Key:    n.Key,
+			N:      n.ComplexityN,
+		}
+		switch what {
+		case "real":
+			r.Old, r.New = o.RealTime, n.RealTime
+		case "cpu":
+			r.Old, r.New = o.CPUTime, n.CPUTime
+		default:
+			oc, nc := o.Counters[what], n.Counters[what]
+			if oc == nil || nc == nil {
+				continue
+			}
+			r.Old, r.New = *oc, *nc
+			r.Unit = ""
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+// sortRows sorts the rows by: "delta", the largest significant
+// increases first, "p", the smallest p-values first, "name" or "time",
+// the largest new times first. An empty by keeps the order.
+func sortRows(rows []row, by string) {
+	var less func(a, b row) bool
+	switch by {
+	case "delta":
+		delta := func(r row) float64 {
+			c := r.Old.Compare(r.New)
+			if !c.Significant {
+				return 0
+			}
+			return c.Delta
+		}
+		less = func(a, b row) bool { return delta(a) > delta(b) }
+	case "p":
+		p := func(r row) float64 {
+			c := r.Old.Compare(r.New)
+			if c.P == -1 {
+				return math.Inf(1)
+			}
+			return c.P
+		}
+		less = func(a, b row) bool { return p(a) < p(b) }
+	case "name":
+		less = func(a, b row) bool { return a.Name < b.Name }
+	case "time":
+		less = func(a, b row) bool { return a.New.Mean > b.New.Mean }
+	default:
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+}
+
+func (p Printer) printRow(r row, indent string) {
+	c := r.Old.Compare(r.New)
+	fmt.Fprintf(p.w, "%s%s", indent, r.Name)
+	fmt.Fprintf(p.w, "\t%s\t%s", p.color(deltaColor(c, r.Unit != ""), c.FormatDelta()),
+		c.Note(len(r.Old.RValues), len(r.New.RValues)))
+	fmt.Fprintf(p.w, "\t%.2f%s\t%.2f%s", r.Old.Mean, r.Unit, r.New.Mean, r.Unit)
+	if p.Dist != "" {
+		old, new := distTexts(p.Dist, r.Old, r.New)
+		fmt.Fprintf(p.w, "\t%s\t%s", old, new)
+	}
+	fmt.Fprintln(p.w)
+}
+
+// groupRows groups the rows by family, keeping the order in which the
+// families are first seen.
+func groupRows(rows []row) [][]row {
+	var groups [][]row
+	index := make(map[string]int)
+	for _, r := range rows {
+		i, ok := index[r.Family]
+		if !ok {
+			i = len(groups)
+			index[r.Family] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups
+}
+
+// printFamily prints the summary of a family followed by its rows: the
+// geometric mean and the median of the changes in mean and the number of
+// significant changes.
+func (p Printer) printFamily(rows []row) {
+	var deltas []float64
+	logSum, nlog := 0.0, 0
+	changed := 0
+	for _, r := range rows {
+		c := r.Old.Compare(r.New)
+		if c.Significant {
+			changed++
+		}
+		if r.Old.Mean > 0 && r.New.Mean > 0 {
+			logSum += math.Log(r.New.Mean / r.Old.Mean)
+			nlog++
+		}
+		if r.Old.Mean != 0 {
+			deltas = append(deltas, (r.New.Mean-r.Old.Mean)/r.Old.Mean*100.0)
+		}
+	}
+	sort.Float64s(deltas)
+
+	geomean := "~"
+	median := "~"
+	color := colorNone
+	if changed > 0 {
+		if nlog > 0 {
+			g := (math.Exp(logSum/float64(nlog)) - 1) * 100.0
+			geomean = fmt.Sprintf("%+.2f%%", g)
+			color = deltaColor(Comparison{Delta: g, Significant: true}, rows[0].Unit != "")
+		}
+		if len(deltas) > 0 {
+			median = fmt.Sprintf("%+.2f%%", Percentile(deltas, 0.5))
+		}
+	}
+
+	if p.OnlySignificant && changed == 0 {
+		return
+	}
+
+	fmt.Fprintf(p.w, "%s\t%s\t(geomean, median %s, %d/%d changed)\t\t",
+		rows[0].Family, p.color(color, geomean), median, changed, len(rows))
+	if p.Dist != "" {
+		fmt.Fprint(p.w, "\t\t")
+	}
+	fmt.Fprintln(p.w)
+
+	if p.Collapse && changed == 0 {
+		return
+	}
+	for _, r := range rows {
+		if p.OnlySignificant && !r.Old.Compare(r.New).Significant {
+			continue
+		}
+		p.printRow(r, "  ")
+	}
+}
+
+// failures returns the old and new metrics of the benchmarks which have
+// failed or skipped repetitions in old or new.
+func failures(old, new []Metric) [][2]Metric {
+	var pairs [][2]Metric
+	index := metricIndex(new)
+	for _, o := range old {
+		i, ok := index[o.KeyString]
+		if !ok {
+			continue
+		}
+		n := new[i]
+		if o.Failed+o.Skipped+n.Failed+n.Skipped > 0 {
+			pairs = append(pairs, [2]Metric{o, n})
+		}
+	}
+	return pairs
+}
+
+// PrintFailures prints the benchmarks returned by failures, noting the
+// ones which fail only in new(NEW FAILURE) or only in old(FIXED).
+func (p Printer) PrintFailures(pairs [][2]Metric) error {
+	title := "failures"
+	fmt.Fprintf(p.w, "%s\t%s\told\tnew\n", title, p.color(colorNone, "note"))
+	fmt.Fprintf(p.w, "%s\t%s\t---\t---\n", strings.Repeat("-", len(title)), p.color(colorNone, "----"))
+
+	for _, pair := range pairs {
+		o, n := pair[0], pair[1]
+
+		note, color := "~", colorNone
+		switch {
+		case o.Failed == 0 && n.Failed > 0:
+			note, color = "NEW FAILURE", colorRed
+		case o.Failed > 0 && n.Failed == 0:
+			note, color = "FIXED", colorGreen
+		}
+
+		fmt.Fprintf(p.w, "%s\t%s\t%s\t%s\n", n.Name, p.color(color, note), o.Status(), n.Status())
+	}
+
+	return p.w.Flush()
+}
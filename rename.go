@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// A Rename replaces the matches of a regex in the names of the old
+// benchmarks, so they match the names of the new ones.
+type Rename struct {
+	Re   *regexp.Regexp
+	Repl string
+}
+
+// parseRename parses a rename given as regex=>replacement. The
+// replacement can refer to the submatches of the regex with $1, ${name}.
+func parseRename(s string) (Rename, error) {
+	i := strings.Index(s, "=>")
+	if i <= 0 {
+		return Rename{}, fmt.Errorf("expected regex=>replacement, got '%s'", s)
+	}
+	re, err := regexp.Compile(strings.TrimSpace(s[:i]))
+	if err != nil {
+		return Rename{}, err
+	}
+	return Rename{Re: re, Repl: strings.TrimSpace(s[i+2:])}, nil
+}
+
+// loadRenames reads a file with one rename per line. Empty lines and
+// lines starting with # are ignored.
+func loadRenames(path string) ([]Rename, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var renames []Rename
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		r, err := parseRename(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		renames = append(renames, r)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return renames, nil
+}
+
+// applyRenames applies the renames, in order, to the names of the
+// benchmarks.
+func applyRenames(renames []Rename, benchmarks []Benchmark) {
+	for i := range benchmarks {
+		b := &benchmarks[i]
+		for _, r := range renames {
+			b.Name = r.Re.ReplaceAllString(b.Name, r.Repl)
+			b.RunName = r.Re.ReplaceAllString(b.RunName, r.Repl)
+		}
+	}
+}
+
+// renamesFlag is a flag.Value which collects renames.
+type renamesFlag []Rename
+
+func (r *renamesFlag) String() string {
+	var s []string
+	for _, v := range *r {
+		s = append(s, v.Re.String()+"=>"+v.Repl)
+	}
+	return strings.Join(s, ",")
+}
+
+func (r *renamesFlag) Set(s string) error {
+	v, err := parseRename(s)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, v)
+	return nil
+}
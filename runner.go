@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const runUsageExtra = `
+Runs the two google benchmark executables alternately, -rounds times,
+each time with -batch repetitions, so that the noise(e.g. thermal
+throttling, background activity) affects both of them in the same way.
+The results are merged and compared like the results given as files.
+
+With -adaptive, -rounds is the number of rounds after which the
+benchmarks are compared, and then only the inconclusive ones are run
+again, one round at a time, until all of them are conclusive or
+-max-rounds is reached. A benchmark is conclusive when its p-value is
+above -stop-p, i.e. more repetitions are unlikely to show a change. The
+benchmarks which seem to have changed run until -max-rounds: stopping
+them as soon as they are significant would test them again after every
+round and report many more changes which are only noise. The printed
+p-values are computed on samples whose size depends on the previous
+comparisons, they are not the p-values of samples of a fixed size. The
+complexities(-complexity) are the ones of the last round which ran all
+the benchmarks.
+
+The arguments after -- are given to both executables, e.g.
+    gbenchdiff run ./old/bench ./new/bench -- --benchmark_filter=BM_foo
+`
+
+func runUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s run [options] old-bench new-bench [-- bench args]\n", os.Args[0])
+		fmt.Fprint(os.Stderr, "options:\n")
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, runUsageExtra)
+		os.Exit(1)
+	}
+}
+
+// runOptions are the options of the commands which run the benchmark
+// executables.
+type runOptions struct {
+	rounds    int
+	batch     int
+	verbose   bool
+	adaptive  bool
+	maxRounds int
+	stopP     float64
+}
+
+// register adds the flags of the options to fs.
+func (o *runOptions) register(fs *flag.FlagSet) {
+	fs.IntVar(&o.rounds, "rounds", 5, "number of times each executable is run")
+	fs.IntVar(&o.batch, "batch", 2, "number of repetitions of each run")
+	fs.BoolVar(&o.verbose, "v", false, "print the output of the executables")
+	fs.BoolVar(&o.adaptive, "adaptive", false, "run again only the benchmarks which may have changed")
+	fs.IntVar(&o.maxRounds, "max-rounds", 20, "with -adaptive, the maximum number of rounds")
+	fs.Float64Var(&o.stopP, "stop-p", 0.5, "with -adaptive, the p-value above which a benchmark is considered unchanged")
+}
+
+// check validates the options after the flags are parsed.
+func (o *runOptions) check() error {
+	if o.rounds < 1 || o.batch < 1 {
+		return errors.New("-rounds and -batch must be at least 1")
+	}
+	if o.adaptive && o.maxRounds < o.rounds {
+		return errors.New("-max-rounds must be at least -rounds")
+	}
+	if o.stopP <= alpha || o.stopP > 1 {
+		return fmt.Errorf("-stop-p must be in (%g, 1]", alpha)
+	}
+	return nil
+}
+
+// runCmd implements the run command.
+func runCmd(args []string) error {
+	var ro runOptions
+	var opts diffOptions
+
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	opts.register(fs)
+	ro.register(fs)
+	fs.Usage = runUsage(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	args = fs.Args()
+	if len(args) < 2 {
+		fs.Usage()
+	}
+	if err := ro.check(); err != nil {
+		return err
+	}
+	if err := opts.init(); err != nil {
+		return err
+	}
+
+	benchArgs := args[2:]
+	if len(benchArgs) > 0 && benchArgs[0] == "--" {
+		benchArgs = benchArgs[1:]
+	}
+
+	oldRes, newRes, err := runBenchmarks(args[0], args[1], benchArgs, &ro, &opts)
+	if err != nil {
+		return err
+	}
+	return diff(oldRes, newRes, &opts)
+}
+
+// runBenchmarks runs the old and new executables as set by ro and
+// returns their results.
+func runBenchmarks(oldExe, newExe string, benchArgs []string, ro *runOptions, opts *diffOptions) (Result, Result, error) {
+	r, err := NewRunner(benchArgs, ro.verbose)
+	if err != nil {
+		return Result{}, Result{}, err
+	}
+	defer r.Close()
+
+	oldRes, newRes, err := r.Interleave(oldExe, newExe, ro.rounds, ro.batch)
+	if err != nil {
+		return Result{}, Result{}, err
+	}
+
+	if ro.adaptive {
+		for i := ro.rounds; i < ro.maxRounds; i++ {
+			names, err := inconclusive(oldRes, newRes, opts, ro.stopP)
+			if err != nil {
+				return Result{}, Result{}, err
+			}
+			if len(names) == 0 {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "round %d/%d: %d inconclusive\n", i+1, ro.maxRounds, len(names))
+			if err := r.round(i, oldExe, newExe, ro.batch, nameFilter(names), &oldRes, &newRes); err != nil {
+				return Result{}, Result{}, err
+			}
+		}
+	}
+
+	return oldRes, newRes, nil
+}
+
+// A Runner runs google benchmark executables and collects their results.
+type Runner struct {
+	// Args are given to the executables, in addition to the ones
+	// needed to get the results.
+	Args []string
+	// Output receives the output of the executables.
+	Output io.Writer
+
+	dir string
+}
+
+// NewRunner returns a Runner which gives args to the executables and
+// prints their output on stderr if verbose is true. It must be closed
+// after use.
+func NewRunner(args []string, verbose bool) (*Runner, error) {
+	dir, err := os.MkdirTemp("", "gbenchdiff")
+	if err != nil {
+		return nil, err
+	}
+	r := &Runner{
+		Args:   args,
+		Output: io.Discard,
+		dir:    dir,
+	}
+	if verbose {
+		r.Output = os.Stderr
+	}
+	return r, nil
+}
+
+// Close removes the temporary files of r.
+func (r *Runner) Close() error {
+	return os.RemoveAll(r.dir)
+}
+
+// Run runs exe with the given number of repetitions and returns its
+// results. If filter is not empty, only the benchmarks matching it are
+// run.
+func (r *Runner) Run(exe string, reps int, filter string) (Result, error) {
+	out := filepath.Join(r.dir, "out.json")
+
+	args := append([]string(nil), r.Args...)
+	args = append(args,
+		"--benchmark_out="+out,
+		"--benchmark_out_format=json",
+		fmt.Sprintf("--benchmark_repetitions=%d", reps),
+	)
+	if filter != "" {
+		args = append(args, "--benchmark_filter="+filter)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = r.Output
+	cmd.Stderr = io.MultiWriter(r.Output, &stderr)
+	if err := cmd.Run(); err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return Result{}, fmt.Errorf("%s: %w: %s", exe, err, msg)
+		}
+		return Result{}, fmt.Errorf("%s: %w", exe, err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return Result{}, err
+	}
+	var res Result
+	if err := json.Unmarshal(data, &res); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", exe, err)
+	}
+	return res, nil
+}
+
+// Interleave runs oldExe and newExe alternately for the given number of
+// rounds, swapping their order every round, and returns their merged
+// results.
+func (r *Runner) Interleave(oldExe, newExe string, rounds, reps int) (Result, Result, error) {
+	var oldRes, newRes Result
+	for i := 0; i < rounds; i++ {
+		fmt.Fprintf(os.Stderr, "round %d/%d\n", i+1, rounds)
+		if err := r.round(i, oldExe, newExe, reps, "", &oldRes, &newRes); err != nil {
+			return Result{}, Result{}, err
+		}
+	}
+	return oldRes, newRes, nil
+}
+
+// round runs oldExe and newExe once, in an order which depends on the
+// round number i, and merges their results into oldRes and newRes.
+func (r *Runner) round(i int, oldExe, newExe string, reps int, filter string, oldRes, newRes *Result) error {
+	order := []struct {
+		exe string
+		res *Result
+	}{{oldExe, oldRes}, {newExe, newRes}}
+	if i%2 == 1 {
+		order[0], order[1] = order[1], order[0]
+	}
+
+	for _, o := range order {
+		res, err := r.Run(o.exe, reps, filter)
+		if err != nil {
+			return err
+		}
+		mergeResult(o.res, res, filter == "")
+	}
+	return nil
+}
+
+// inconclusive returns the names of the benchmarks, both old and new,
+// which don't have a p-value above stopP. The ones which changed
+// significantly are included: the comparison is repeated after every
+// round, so stopping at the first significant one would make the false
+// positives much more likely than alpha.
+func inconclusive(oldRes, newRes Result, o *diffOptions, stopP float64) ([]string, error) {
+	// the old names are needed to run the old benchmarks again
+	renamed := append([]Benchmark(nil), oldRes.Benchmarks...)
+	applyRenames(o.renames, renamed)
+	oldNames := make(map[string]string)
+	for i, b := range renamed {
+		oldNames[b.Name] = oldRes.Benchmarks[i].Name
+	}
+
+	oldMetrics := GetMetrics(renamed, o.filter)
+	newMetrics := GetMetrics(newRes.Benchmarks, o.filter)
+
+	whats := []string{"real"}
+	if o.withCPUTime {
+		whats = append(whats, "cpu")
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, what := range whats {
+		rows, err := matchRows(what, oldMetrics, newMetrics)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			c := r.Old.Compare(r.New)
+			if c.P > stopP || seen[r.Name] {
+				continue
+			}
+			seen[r.Name] = true
+			names = append(names, r.Name)
+			if old := oldNames[r.Name]; old != "" && old != r.Name {
+				names = append(names, old)
+			}
+		}
+	}
+	return names, nil
+}
+
+// nameFilter returns a --benchmark_filter which matches exactly the
+// given benchmark names.
+func nameFilter(names []string) string {
+	var quoted []string
+	for _, n := range names {
+		quoted = append(quoted, regexp.QuoteMeta(n))
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// mergeResult adds the repetitions of src to dst. The aggregates of src
+// replace the ones of dst with the same name, since they are computed
+// only from the repetitions of one run, unless aggregates is false: the
+// aggregates of a run of some of the instances, e.g. the complexity of
+// a family, are not comparable to the ones of a full run.
+func mergeResult(dst *Result, src Result, aggregates bool) {
+	if dst.Context.Date == "" {
+		dst.Context = src.Context
+	}
+
+	replaced := make(map[string]bool)
+	if aggregates {
+		for _, b := range src.Benchmarks {
+			if b.RunType != "iteration" {
+				replaced[b.Name] = true
+			}
+		}
+	}
+
+	reps := make(map[string]uint64)
+	var benchmarks []Benchmark
+	for _, b := range dst.Benchmarks {
+		if b.RunType == "iteration" {
+			reps[b.Name]++
+		} else if replaced[b.Name] {
+			continue
+		}
+		benchmarks = append(benchmarks, b)
+	}
+	for _, b := range src.Benchmarks {
+		if b.RunType == "iteration" {
+			b.RepetitionIndex = reps[b.Name]
+			reps[b.Name]++
+		} else if !aggregates {
+			continue
+		}
+		benchmarks = append(benchmarks, b)
+	}
+	for i := range benchmarks {
+		if benchmarks[i].RunType == "iteration" {
+			benchmarks[i].Repetitions = reps[benchmarks[i].Name]
+		}
+	}
+	dst.Benchmarks = benchmarks
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeResult(t *testing.T) {
+	run := func(n float64, names ...string) Result {
+		var res Result
+		for _, name := range names {
+			res.Benchmarks = append(res.Benchmarks, Benchmark{
+				Name:     name,
+				RunName:  name,
+				RunType:  "iteration",
+				RealTime: n,
+			})
+		}
+		res.Benchmarks = append(res.Benchmarks,
+			Benchmark{Name: "BM_f_BigO", RunName: "BM_f", RunType: "aggregate", AggregateName: "BigO", RealCoefficient: n},
+			Benchmark{Name: "BM_f_RMS", RunName: "BM_f", RunType: "aggregate", AggregateName: "RMS", RMS: n},
+		)
+		return res
+	}
+
+	var res Result
+	mergeResult(&res, run(1, "BM_f/8", "BM_f/64"), true)
+	mergeResult(&res, run(2, "BM_f/8", "BM_f/64"), true)
+	// a filtered run, its complexity is fitted on BM_f/64 only
+	mergeResult(&res, run(3, "BM_f/64"), false)
+
+	type rep struct {
+		name     string
+		index    uint64
+		reps     uint64
+		realTime float64
+	}
+	var got []rep
+	coefficients := make(map[string]float64)
+	for _, b := range res.Benchmarks {
+		switch b.AggregateName {
+		case "":
+			got = append(got, rep{b.Name, b.RepetitionIndex, b.Repetitions, b.RealTime})
+		case "BigO":
+			coefficients[b.Name] = b.RealCoefficient
+		case "RMS":
+			coefficients[b.Name] = b.RMS
+		}
+	}
+	want := []rep{
+		{"BM_f/8", 0, 2, 1},
+		{"BM_f/64", 0, 3, 1},
+		{"BM_f/8", 1, 2, 2},
+		{"BM_f/64", 1, 3, 2},
+		{"BM_f/64", 2, 3, 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repetitions = %v, want %v", got, want)
+	}
+	wantCoefficients := map[string]float64{"BM_f_BigO": 2, "BM_f_RMS": 2}
+	if !reflect.DeepEqual(coefficients, wantCoefficients) {
+		t.Errorf("aggregates = %v, want %v", coefficients, wantCoefficients)
+	}
+	if n := len(res.Benchmarks); n != 7 {
+		t.Errorf("got %d benchmarks, want 7", n)
+	}
+}
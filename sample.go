@@ -3,8 +3,6 @@ package main
 import (
 	"errors"
 	"fmt"
-	"io"
-	"regexp"
 	"sort"
 
 	"bandr.me/p/gbenchdiff/internal/stats"
@@ -13,10 +11,32 @@ import (
 const alpha = 0.05
 
 type Metric struct {
+	Counters map[string]*Sample
 	Name     string
 	TimeUnit string
-	RealTime Sample
-	CPUTime  Sample
+	Key      BenchName
+	// KeyString is Key.String(), which identifies the benchmark when
+	// matching metrics.
+	KeyString string
+	// Message is the error or skip message of the last failed or
+	// skipped repetition.
+	Message string
+	// ComplexityN is the N set with state.SetComplexityN().
+	ComplexityN int64
+	// Failed and Skipped are the number of repetitions which failed
+	// with state.SkipWithError() or were skipped with
+	// state.SkipWithMessage(). They are not part of the samples.
+	Failed  int
+	Skipped int
+	// MinIterations and MaxIterations are the bounds of the number of
+	// iterations of the successful repetitions.
+	MinIterations uint64
+	MaxIterations uint64
+	// SingleRun is set when the repetitions are single runs of a
+	// program, see Benchmark.SingleRun.
+	SingleRun bool
+	RealTime  Sample
+	CPUTime   Sample
 }
 
 type Sample struct {
@@ -45,68 +65,135 @@ func (s *Sample) ComputeStats() {
 	s.Mean = Mean(s.RValues)
 }
 
-func (o Sample) Print(w io.Writer, n Sample, tu string) {
-	u, err := stats.MannWhitneyUTest(o.RValues, n.RValues, stats.LocationDiffers)
+// A Comparison is the result of the significance test between an old
+// and a new sample.
+type Comparison struct {
+	// Err is set when the test could not be performed.
+	Err error
+	// P is the p-value of the test, -1 if not available.
+	P float64
+	// Delta is the % change in mean from old to new.
+	Delta float64
+	// Significant is true if P < alpha.
+	Significant bool
+}
 
-	pval := u.P
+// Compare performs the significance test between o and n.
+func (o Sample) Compare(n Sample) Comparison {
+	u, err := stats.MannWhitneyUTest(o.RValues, n.RValues, stats.LocationDiffers)
 
-	delta := "~"
-	note := ""
+	c := Comparison{
+		Err: err,
+		P:   -1,
+	}
+	if u != nil {
+		c.P = u.P
+	}
+	if n.Mean != o.Mean {
+		c.Delta = ((n.Mean - o.Mean) / o.Mean) * 100.0
+	}
+	c.Significant = err == nil && c.P < alpha
+	return c
+}
 
+// Note describes c: why the test could not be performed or the p-value
+// and the sizes of the old and new samples.
+func (c Comparison) Note(oldN, newN int) string {
 	switch {
-	case errors.Is(err, stats.ErrZeroVariance):
-		note = "(zero variance)"
-	case errors.Is(err, stats.ErrSampleSize):
-		note = "(too few samples)"
-	case errors.Is(err, stats.ErrSamplesEqual):
-		note = "(all equal)"
-	case err != nil:
-		note = fmt.Sprintf("(%s)", err)
-	case pval < alpha:
-		if n.Mean == o.Mean {
-			delta = "0.00%"
-		} else {
-			pct := ((n.Mean - o.Mean) / o.Mean) * 100.0
-			delta = fmt.Sprintf("%+.2f%%", pct)
-		}
+	case errors.Is(c.Err, stats.ErrZeroVariance):
+		return "(zero variance)"
+	case errors.Is(c.Err, stats.ErrSampleSize):
+		return "(too few samples)"
+	case errors.Is(c.Err, stats.ErrSamplesEqual):
+		return "(all equal)"
+	case c.Err != nil:
+		return fmt.Sprintf("(%s)", c.Err)
+	case c.P != -1:
+		return fmt.Sprintf("(p=%0.2f n=%d+%d)", c.P, oldN, newN)
 	}
+	return ""
+}
 
-	if note == "" && pval != -1 {
-		note = fmt.Sprintf("(p=%0.2f n=%d+%d)", pval, len(o.RValues), len(n.RValues))
+// FormatDelta returns the % change in mean if c is significant, ~
+// otherwise.
+func (c Comparison) FormatDelta() string {
+	switch {
+	case !c.Significant:
+		return "~"
+	case c.Delta == 0:
+		return "0.00%"
 	}
-
-	fmt.Fprintf(w, "\t%s\t%s", delta, note)
-	fmt.Fprintf(w, "\t%.2f%s\t%.2f%s", o.Mean, tu, n.Mean, tu)
+	return fmt.Sprintf("%+.2f%%", c.Delta)
 }
 
-func findMetric(m []Metric, name string) int {
+// metricIndex returns the indexes of the metrics of m by their
+// KeyString.
+func metricIndex(m []Metric) map[string]int {
+	index := make(map[string]int, len(m))
 	for i := range m {
-		if m[i].Name == name {
-			return i
-		}
+		index[m[i].KeyString] = i
 	}
-	return -1
+	return index
 }
 
-func GetMetrics(benchmarks []Benchmark, filterRe *regexp.Regexp) []Metric {
+func GetMetrics(benchmarks []Benchmark, filter Filter) []Metric {
 	var metrics []Metric
+	index := make(map[string]int)
 	for _, b := range benchmarks {
-		if filterRe != nil && !filterRe.MatchString(b.Name) {
+		if b.RunType != "iteration" {
 			continue
 		}
-		if b.RunType != "iteration" {
+		runName := b.RunName
+		if runName == "" {
+			runName = b.Name
+		}
+		key := ParseBenchName(runName, b.Threads)
+		if !filter.Match(b.Name, key) {
 			continue
 		}
-		i := findMetric(metrics, b.Name)
-		if i == -1 {
+		k := key.String()
+		i, ok := index[k]
+		if !ok {
+			i = len(metrics)
+			index[k] = i
 			metrics = append(metrics, Metric{
-				Name:     b.Name,
-				TimeUnit: b.TimeUnit,
+				Name:      b.Name,
+				TimeUnit:  b.TimeUnit,
+				Key:       key,
+				KeyString: k,
+				SingleRun: b.SingleRun,
 			})
-			i = len(metrics) - 1
+		}
+		if b.ErrorOccurred {
+			metrics[i].Failed++
+			metrics[i].Message = b.ErrorMessage
+			continue
+		}
+		if b.Skipped {
+			metrics[i].Skipped++
+			metrics[i].Message = b.SkipMessage
+			continue
+		}
+		metrics[i].ComplexityN = b.ComplexityN
+		if metrics[i].MinIterations == 0 || b.Iterations < metrics[i].MinIterations {
+			metrics[i].MinIterations = b.Iterations
+		}
+		if b.Iterations > metrics[i].MaxIterations {
+			metrics[i].MaxIterations = b.Iterations
 		}
 		metrics[i].RealTime.Values = append(metrics[i].RealTime.Values, b.RealTime)
 		metrics[i].CPUTime.Values = append(metrics[i].CPUTime.Values, b.CPUTime)
+		for k, v := range b.Counters {
+			if metrics[i].Counters == nil {
+				metrics[i].Counters = make(map[string]*Sample)
+			}
+			c, ok := metrics[i].Counters[k]
+			if !ok {
+				c = &Sample{}
+				metrics[i].Counters[k] = c
+			}
+			c.Values = append(c.Values, v)
+		}
 	}
 	for i := range metrics {
 		r := metrics[i].RealTime.Values
@@ -118,6 +205,51 @@ func GetMetrics(benchmarks []Benchmark, filterRe *regexp.Regexp) []Metric {
 		sort.Float64s(c)
 		metrics[i].CPUTime.Values = c
 		metrics[i].CPUTime.ComputeStats()
+
+		for _, c := range metrics[i].Counters {
+			sort.Float64s(c.Values)
+			c.ComputeStats()
+		}
 	}
 	return metrics
 }
+
+// Ok reports whether m has at least one successful repetition.
+func (m Metric) Ok() bool {
+	return len(m.RealTime.Values) > 0
+}
+
+// Repetitions returns the number of repetitions of m, including the
+// failed and skipped ones.
+func (m Metric) Repetitions() int {
+	return len(m.RealTime.Values) + m.Failed + m.Skipped
+}
+
+// Status describes the failed and skipped repetitions of m.
+func (m Metric) Status() string {
+	runs := m.Repetitions()
+	switch {
+	case m.Failed > 0:
+		return fmt.Sprintf("failed %d/%d: %s", m.Failed, runs, m.Message)
+	case m.Skipped > 0:
+		return fmt.Sprintf("skipped %d/%d: %s", m.Skipped, runs, m.Message)
+	default:
+		return "ok"
+	}
+}
+
+// CounterNames returns the sorted names of all the counters found in m.
+func CounterNames(m []Metric) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for i := range m {
+		for k := range m[i].Counters {
+			if !seen[k] {
+				seen[k] = true
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetMetricsFailedSkipped(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{
+			format: "json",
+			input: `{
+  "context": {"date": "2023-02-11T10:00:00+02:00", "num_cpus": 8},
+  "benchmarks": [
+    {"name": "BM_a", "run_name": "BM_a", "run_type": "iteration", "repetitions": 4, "repetition_index": 0,
+     "threads": 1, "iterations": 1000, "real_time": 100, "cpu_time": 99, "time_unit": "ns"},
+    {"name": "BM_a", "run_name": "BM_a", "run_type": "iteration", "repetitions": 4, "repetition_index": 1,
+     "threads": 1, "error_occurred": false, "error_message": "skipped",
+     "iterations": 0, "real_time": 0, "cpu_time": 0, "time_unit": "ns"},
+    {"name": "BM_a", "run_name": "BM_a", "run_type": "iteration", "repetitions": 4, "repetition_index": 2,
+     "threads": 1, "error_occurred": true, "error_message": "failed",
+     "iterations": 0, "real_time": 0, "cpu_time": 0, "time_unit": "ns"},
+    {"name": "BM_a", "run_name": "BM_a", "run_type": "iteration", "repetitions": 4, "repetition_index": 3,
+     "threads": 1, "iterations": 1000, "real_time": 102, "cpu_time": 101, "time_unit": "ns"}
+  ]
+}`,
+		},
+		{
+			format: "csv",
+			input: `name,iterations,real_time,cpu_time,time_unit,bytes_per_second,items_per_second,label,error_occurred,error_message
+"BM_a",1000,100,99,ns,,,,,
+"BM_a",,,,,,,,false,"skipped"
+"BM_a",,,,,,,,true,"failed"
+"BM_a",1000,102,101,ns,,,,,
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			res, err := readResult(tt.format, strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metrics := GetMetrics(res.Benchmarks, Filter{})
+			if len(metrics) != 1 {
+				t.Fatalf("got %d metrics, want 1", len(metrics))
+			}
+			m := metrics[0]
+			if got := m.RealTime.Values; len(got) != 2 || got[0] != 100 || got[1] != 102 {
+				t.Errorf("real times = %v, want [100 102]", got)
+			}
+			if m.Skipped != 1 || m.Failed != 1 {
+				t.Errorf("skipped, failed = %d, %d, want 1, 1", m.Skipped, m.Failed)
+			}
+			if m.Repetitions() != 4 {
+				t.Errorf("repetitions = %d, want 4", m.Repetitions())
+			}
+		})
+	}
+}
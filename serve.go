@@ -0,0 +1,221 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const serveUsageExtra = `
+Starts a local web server to explore the comparisons of the given
+result files in a browser: pick any two of them, sort and filter the
+comparison table, show the distributions of the repetitions of a
+benchmark and the differences between the contexts. Everything is
+served by gbenchdiff itself, nothing is loaded from the internet.
+`
+
+func serveUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [options] file...\n", os.Args[0])
+		fmt.Fprint(os.Stderr, "options:\n")
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, serveUsageExtra)
+		os.Exit(1)
+	}
+}
+
+//go:embed assets
+var assets embed.FS
+
+// A server serves the comparisons of a set of results.
+type server struct {
+	labels    []string
+	results   []Result
+	filter    Filter
+	ctxLevels map[string]string
+}
+
+// apiSample is a sample as sent to the browser.
+type apiSample struct {
+	Values   []float64 `json:"values"`
+	Outliers []float64 `json:"outliers"`
+	Mean     float64   `json:"mean"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+}
+
+// apiRow is a row of the comparison table.
+type apiRow struct {
+	Name        string    `json:"name"`
+	Family      string    `json:"family"`
+	Unit        string    `json:"unit"`
+	Note        string    `json:"note"`
+	Old         apiSample `json:"old"`
+	New         apiSample `json:"new"`
+	Delta       float64   `json:"delta"`
+	P           float64   `json:"p"`
+	Significant bool      `json:"significant"`
+}
+
+func newAPISample(s Sample) apiSample {
+	return apiSample{
+		Values:   s.RValues,
+		Outliers: outliers(s),
+		Mean:     s.Mean,
+		Min:      s.Min,
+		Max:      s.Max,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleResults sends the labels of the results and the metrics which
+// can be compared.
+func (s *server) handleResults(w http.ResponseWriter, r *http.Request) {
+	metrics := []string{"real", "cpu"}
+	seen := make(map[string]bool)
+	for _, res := range s.results {
+		for _, name := range CounterNames(GetMetrics(res.Benchmarks, s.filter)) {
+			if !seen[name] {
+				seen[name] = true
+				metrics = append(metrics, name)
+			}
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"results": s.labels,
+		"metrics": metrics,
+	})
+}
+
+// result returns the result with the index given in the query parameter
+// key.
+func (s *server) result(r *http.Request, key string) (Result, error) {
+	i, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil || i < 0 || i >= len(s.results) {
+		return Result{}, fmt.Errorf("invalid %s result", key)
+	}
+	return s.results[i], nil
+}
+
+// handleCompare sends the comparison of the old and new results for the
+// metric given in the query and the differences of their contexts.
+func (s *server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	oldRes, err := s.result(r, "old")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newRes, err := s.result(r, "new")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	what := r.URL.Query().Get("metric")
+	if what == "" {
+		what = "real"
+	}
+
+	rows, err := matchRows(what, GetMetrics(oldRes.Benchmarks, s.filter), GetMetrics(newRes.Benchmarks, s.filter))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	apiRows := []apiRow{}
+	for _, row := range rows {
+		c := row.Old.Compare(row.New)
+		a := apiRow{
+			Name:        row.Name,
+			Family:      row.Family,
+			Unit:        row.Unit,
+			Note:        c.Note(len(row.Old.RValues), len(row.New.RValues)),
+			Old:         newAPISample(row.Old),
+			New:         newAPISample(row.New),
+			Delta:       c.Delta,
+			P:           c.P,
+			Significant: c.Significant,
+		}
+		if math.IsInf(a.Delta, 0) || math.IsNaN(a.Delta) {
+			a.Delta = 0
+		}
+		apiRows = append(apiRows, a)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"rows":    apiRows,
+		"context": DiffContexts(oldRes.Context, newRes.Context, s.ctxLevels),
+	})
+}
+
+// serveCmd implements the serve command.
+func serveCmd(args []string) error {
+	var fAddr string
+	var fFormat string
+	var fo filterOptions
+	ctxLevels := make(paramsFlag)
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.StringVar(&fAddr, "addr", "localhost:8080", "the address to listen on")
+	flags.StringVar(&fFormat, "format", "auto", "input format, one of: "+inputFormatNames())
+	flags.Var(ctxLevels, "ctx", "set the level of a context field, as field=error|warn|ignore(can be repeated)")
+	fo.register(flags)
+	flags.Usage = serveUsage(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+	}
+	if err := checkContextLevels(ctxLevels); err != nil {
+		return err
+	}
+	if err := fo.init(); err != nil {
+		return err
+	}
+
+	s := &server{
+		filter:    fo.filter,
+		ctxLevels: ctxLevels,
+	}
+	for _, path := range flags.Args() {
+		if path == "-" {
+			return errors.New("serve cannot read from stdin")
+		}
+		res, err := loadResult(path, fFormat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		s.labels = append(s.labels, filepath.Base(path))
+		s.results = append(s.results, res)
+	}
+
+	static, err := fs.Sub(assets, "assets")
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/api/results", s.handleResults)
+	mux.HandleFunc("/api/compare", s.handleCompare)
+
+	fmt.Fprintf(os.Stderr, "serving on http://%s\n", fAddr)
+	return http.ListenAndServe(fAddr, mux)
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const storeUsageExtra = `
+Keeps the results in a local store, a directory with the result files
+and an index(index.json) of their revisions, dates, hosts and tags. The
+directory is given with -store, by default $GBENCHDIFF_STORE or
+.gbenchdiff.
+
+Commands:
+    add [-rev rev] [-tag tag]... file    add a result file
+    list                                 list all the entries
+    query [filters]                      list the entries matching the filters
+    prune [-keep n] [filters]            remove the entries matching the filters
+    diff [options] old-label new-label   compare two entries
+
+The revision defaults to the git HEAD of the current directory, the date
+and the host are read from the context of the result.
+
+The filters are -rev(a revision prefix), -host, -tag(can be repeated,
+all the tags must match), -since and -until(dates as 2006-01-02 or
+RFC 3339). prune needs at least one filter or -keep and keeps the newest
+-keep matching entries.
+
+The entries are ordered by the dates of their results, the ones without
+a known date first and the ones with the same date in the order in which
+they were added.
+
+A label is an entry id, a tag or a revision prefix. If several entries
+match, the newest one is used.
+`
+
+func storeUsage(fs *flag.FlagSet, cmd string) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s store %s\n", os.Args[0], cmd)
+		if fs != nil {
+			fmt.Fprint(os.Stderr, "options:\n")
+			fs.PrintDefaults()
+		}
+		fmt.Fprint(os.Stderr, storeUsageExtra)
+		os.Exit(1)
+	}
+}
+
+// A StoreEntry is a result in the store.
+type StoreEntry struct {
+	ID       int      `json:"id"`
+	File     string   `json:"file"`
+	Format   string   `json:"format"`
+	Revision string   `json:"revision"`
+	Date     string   `json:"date"`
+	Host     string   `json:"host"`
+	Tags     []string `json:"tags"`
+}
+
+// before reports whether e is older than f: e has an earlier date, no
+// known date while f has one or the same date and a lower ID.
+func (e StoreEntry) before(f StoreEntry) bool {
+	ed, eErr := parseDate(e.Date)
+	fd, fErr := parseDate(f.Date)
+	switch {
+	case eErr != nil && fErr == nil:
+		return true
+	case eErr == nil && fErr != nil:
+		return false
+	case eErr == nil && !ed.Equal(fd):
+		return ed.Before(fd)
+	}
+	return e.ID < f.ID
+}
+
+// HasTag reports whether e has the given tag.
+func (e StoreEntry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// A Store is a directory of result files with an index.
+type Store struct {
+	Dir     string
+	Entries []StoreEntry
+}
+
+const storeIndex = "index.json"
+
+// defaultStoreDir returns the store directory used when none is given.
+func defaultStoreDir() string {
+	if dir := os.Getenv("GBENCHDIFF_STORE"); dir != "" {
+		return dir
+	}
+	return ".gbenchdiff"
+}
+
+// OpenStore reads the index of the store in dir. A store which doesn't
+// exist yet is empty.
+func OpenStore(dir string) (*Store, error) {
+	s := &Store{Dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, storeIndex))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.Entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, storeIndex), err)
+	}
+	return s, nil
+}
+
+// save writes the index of s.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.Entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.Dir, storeIndex+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.Dir, storeIndex))
+}
+
+// Add adds e to s, with the result file data saved with the extension
+// ext. The ID and File of e are set by Add.
+func (s *Store) Add(data []byte, ext string, e StoreEntry) (StoreEntry, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return e, err
+	}
+
+	e.ID = 1
+	for _, o := range s.Entries {
+		if o.ID >= e.ID {
+			e.ID = o.ID + 1
+		}
+	}
+	e.File = strconv.Itoa(e.ID) + ext
+	if err := os.WriteFile(filepath.Join(s.Dir, e.File), data, 0o644); err != nil {
+		return e, err
+	}
+
+	s.Entries = append(s.Entries, e)
+	return e, s.save()
+}
+
+// Load reads the result of e.
+func (s *Store) Load(e StoreEntry) (Result, error) {
+	path := filepath.Join(s.Dir, e.File)
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+	return readResult(path, f, e.Format)
+}
+
+// Remove removes the given entries and their files from s.
+func (s *Store) Remove(entries []StoreEntry) error {
+	remove := make(map[int]bool)
+	for _, e := range entries {
+		remove[e.ID] = true
+	}
+
+	var kept []StoreEntry
+	for _, e := range s.Entries {
+		if !remove[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	s.Entries = kept
+	if err := s.save(); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := os.Remove(filepath.Join(s.Dir, e.File))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns the newest entry with the given label: an ID, a tag or
+// a revision prefix.
+func (s *Store) Find(label string) (StoreEntry, error) {
+	if id, err := strconv.Atoi(label); err == nil {
+		for _, e := range s.Entries {
+			if e.ID == id {
+				return e, nil
+			}
+		}
+	}
+	entries := s.Query(StoreQuery{})
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.HasTag(label) || (e.Revision != "" && strings.HasPrefix(e.Revision, label)) {
+			return e, nil
+		}
+	}
+	return StoreEntry{}, fmt.Errorf("no entry with label '%s'", label)
+}
+
+// A StoreQuery selects store entries. Empty fields match all entries.
+type StoreQuery struct {
+	Revision string
+	Host     string
+	Tags     []string
+	Since    time.Time
+	Until    time.Time
+}
+
+// register adds the flags of the query to fs.
+func (q *StoreQuery) register(fs *flag.FlagSet) {
+	fs.StringVar(&q.Revision, "rev", "", "select the entries with revisions starting with the given prefix")
+	fs.StringVar(&q.Host, "host", "", "select the entries from the given host")
+	fs.Var((*tagsFlag)(&q.Tags), "tag", "select the entries with the given tag(can be repeated)")
+	fs.Func("since", "select the entries from the given date or later", func(s string) (err error) {
+		q.Since, err = parseDate(s)
+		return err
+	})
+	fs.Func("until", "select the entries before the given date", func(s string) (err error) {
+		q.Until, err = parseDate(s)
+		return err
+	})
+}
+
+// empty reports whether q matches all the entries.
+func (q *StoreQuery) empty() bool {
+	return q.Revision == "" && q.Host == "" && len(q.Tags) == 0 && q.Since.IsZero() && q.Until.IsZero()
+}
+
+// Match reports whether e is selected by q. The entries without a
+// known date don't match a date range.
+func (q *StoreQuery) Match(e StoreEntry) bool {
+	if q.Revision != "" && !strings.HasPrefix(e.Revision, q.Revision) {
+		return false
+	}
+	if q.Host != "" && e.Host != q.Host {
+		return false
+	}
+	for _, t := range q.Tags {
+		if !e.HasTag(t) {
+			return false
+		}
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		d, err := parseDate(e.Date)
+		if err != nil {
+			return false
+		}
+		if !q.Since.IsZero() && d.Before(q.Since) {
+			return false
+		}
+		if !q.Until.IsZero() && !d.Before(q.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the entries of s selected by q, oldest first.
+func (s *Store) Query(q StoreQuery) []StoreEntry {
+	var entries []StoreEntry
+	for _, e := range s.Entries {
+		if q.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].before(entries[j]) })
+	return entries
+}
+
+// dateLayouts are the layouts of the dates written by google benchmark,
+// old and new, and the ones accepted on the command line.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-07:00",
+	"2006-01-02 15:04:05",
+	"01/02/06 15:04:05",
+	"2006-01-02",
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", s)
+}
+
+// tagsFlag is a flag.Value which collects tags.
+type tagsFlag []string
+
+func (t *tagsFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagsFlag) Set(s string) error {
+	if s == "" {
+		return errors.New("empty tag")
+	}
+	*t = append(*t, s)
+	return nil
+}
+
+// PrintEntries prints the entries as a table.
+func PrintEntries(w io.Writer, entries []StoreEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintf(tw, "id\tdate\trevision\thost\ttags\n")
+	fmt.Fprintf(tw, "--\t----\t--------\t----\t----\n")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.ID, orDash(e.Date), orDash(shortRev(e.Revision)),
+			orDash(e.Host), orDash(strings.Join(e.Tags, ",")))
+	}
+	return tw.Flush()
+}
+
+func shortRev(rev string) string {
+	if len(rev) > 12 {
+		return rev[:12]
+	}
+	return rev
+}
+
+// storeCmd implements the store command.
+func storeCmd(args []string) error {
+	if len(args) < 1 {
+		storeUsage(nil, "add|list|query|prune|diff [options] [args]")()
+	}
+
+	var fStore string
+	fs := flag.NewFlagSet("store "+args[0], flag.ExitOnError)
+	fs.StringVar(&fStore, "store", defaultStoreDir(), "the store directory")
+
+	switch args[0] {
+	case "add":
+		return storeAdd(fs, args[1:], &fStore)
+	case "list":
+		fs.Usage = storeUsage(fs, "list [options]")
+		fs.Parse(args[1:])
+		s, err := OpenStore(fStore)
+		if err != nil {
+			return err
+		}
+		return PrintEntries(os.Stdout, s.Query(StoreQuery{}))
+	case "query":
+		var q StoreQuery
+		q.register(fs)
+		fs.Usage = storeUsage(fs, "query [options]")
+		fs.Parse(args[1:])
+		s, err := OpenStore(fStore)
+		if err != nil {
+			return err
+		}
+		return PrintEntries(os.Stdout, s.Query(q))
+	case "prune":
+		return storePrune(fs, args[1:], &fStore)
+	case "diff":
+		return storeDiff(fs, args[1:], &fStore)
+	default:
+		return fmt.Errorf("unknown store command '%s'", args[0])
+	}
+}
+
+func storeAdd(fs *flag.FlagSet, args []string, store *string) error {
+	var fRev string
+	var fFormat string
+	var fTags tagsFlag
+	fs.StringVar(&fRev, "rev", "", "the revision of the result(default the git HEAD of the current directory)")
+	fs.StringVar(&fFormat, "format", "auto", "input format, one of: "+inputFormatNames())
+	fs.Var(&fTags, "tag", "tag the entry(can be repeated)")
+	fs.Usage = storeUsage(fs, "add [options] file")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	path := fs.Arg(0)
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	res, err := readResult(path, bytes.NewReader(data), fFormat)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if fRev == "" {
+		// not being in a git repository is fine
+		fRev, _ = git("", "rev-parse", "HEAD")
+	}
+
+	s, err := OpenStore(*store)
+	if err != nil {
+		return err
+	}
+	ext := filepath.Ext(path)
+	if path == "-" {
+		ext = ""
+	}
+	e, err := s.Add(data, ext, StoreEntry{
+		Format:   fFormat,
+		Revision: fRev,
+		Date:     res.Context.Date,
+		Host:     res.Context.Hostname,
+		Tags:     fTags,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(e.ID)
+	return nil
+}
+
+func storePrune(fs *flag.FlagSet, args []string, store *string) error {
+	var q StoreQuery
+	var fKeep int
+	var fDryRun bool
+	q.register(fs)
+	fs.IntVar(&fKeep, "keep", 0, "keep the given number of newest matching entries")
+	fs.BoolVar(&fDryRun, "n", false, "print the entries which would be removed, without removing them")
+	fs.Usage = storeUsage(fs, "prune [options]")
+	fs.Parse(args)
+
+	if q.empty() && fKeep <= 0 {
+		return errors.New("prune needs a filter or -keep")
+	}
+
+	s, err := OpenStore(*store)
+	if err != nil {
+		return err
+	}
+	entries := s.Query(q)
+	if fKeep > 0 {
+		if fKeep >= len(entries) {
+			return nil
+		}
+		entries = entries[:len(entries)-fKeep]
+	}
+
+	if err := PrintEntries(os.Stdout, entries); err != nil {
+		return err
+	}
+	if fDryRun {
+		return nil
+	}
+	return s.Remove(entries)
+}
+
+func storeDiff(fs *flag.FlagSet, args []string, store *string) error {
+	var opts diffOptions
+	opts.register(fs)
+	fs.Usage = storeUsage(fs, "diff [options] old-label new-label")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+	}
+	if err := opts.init(); err != nil {
+		return err
+	}
+
+	s, err := OpenStore(*store)
+	if err != nil {
+		return err
+	}
+	var res [2]Result
+	for i, label := range fs.Args() {
+		e, err := s.Find(label)
+		if err != nil {
+			return err
+		}
+		res[i], err = s.Load(e)
+		if err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+	}
+	return diff(res[0], res[1], &opts)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// timeUnitSeconds converts the time units used by google benchmark to
+// seconds.
+var timeUnitSeconds = map[string]float64{
+	"ns": 1e-9,
+	"us": 1e-6,
+	"ms": 1e-3,
+	"s":  1,
+}
+
+// benchThreads returns the number of threads of the benchmark in r.
+func benchThreads(r row) int {
+	if v, ok := r.Key.Param("threads"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return 1
+}
+
+// threadsFamily returns the name of the family of r without the threads
+// modifier.
+func threadsFamily(r row) string {
+	k := r.Key
+	k.Modifiers = nil
+	for _, m := range r.Key.Modifiers {
+		if m.Name != "threads" {
+			k.Modifiers = append(k.Modifiers, m)
+		}
+	}
+	return k.String()
+}
+
+// PrintScaling prints, for every family run with more than one thread
+// count, the real time, the throughput and the speedup and efficiency
+// relative to the smallest thread count, for old and new. The CPU time
+// is not used: google benchmark sums it over the threads, so it stays
+// the same when the threads scale perfectly.
+//
+// A scaling regression is reported when new is significantly slower
+// than old and its efficiency dropped by more than threshold(e.g. 0.1
+// for 10%), even if the times with fewer threads did not change.
+func (p Printer) PrintScaling(old, new []Metric, threshold float64) error {
+	rows, err := matchRows("real", old, new)
+	if err != nil {
+		return err
+	}
+
+	var families []string
+	byFamily := make(map[string][]row)
+	for _, r := range rows {
+		f := threadsFamily(r)
+		if _, ok := byFamily[f]; !ok {
+			families = append(families, f)
+		}
+		byFamily[f] = append(byFamily[f], r)
+	}
+
+	title := "real scaling"
+	fmt.Fprintf(p.w, "%s\tthreads\told\tnew\told ops/s\tnew ops/s\told speedup\tnew speedup\tnote\n", title)
+	fmt.Fprintf(p.w, "%s\t-------\t---\t---\t---------\t---------\t-----------\t-----------\t----\n", strings.Repeat("-", len(title)))
+
+	for _, f := range families {
+		rs := byFamily[f]
+		if len(rs) < 2 {
+			continue
+		}
+		sort.SliceStable(rs, func(i, j int) bool { return benchThreads(rs[i]) < benchThreads(rs[j]) })
+
+		base := rs[0]
+		baseThreads := float64(benchThreads(base))
+		unit, ok := timeUnitSeconds[base.Unit]
+		if !ok {
+			return fmt.Errorf("unknown time unit '%s'", base.Unit)
+		}
+
+		for i, r := range rs {
+			threads := benchThreads(r)
+			scale := float64(threads) / baseThreads
+
+			oSpeedup := base.Old.Mean / r.Old.Mean
+			nSpeedup := base.New.Mean / r.New.Mean
+			oEff := oSpeedup / scale
+			nEff := nSpeedup / scale
+
+			note := "~"
+			c := r.Old.Compare(r.New)
+			if c.Significant && c.Delta > 0 && nEff < oEff*(1-threshold) {
+				note = fmt.Sprintf("SCALING REGRESSION (efficiency %.0f%% -> %.0f%%)", oEff*100, nEff*100)
+			}
+
+			name := ""
+			if i == 0 {
+				name = f
+			}
+			fmt.Fprintf(p.w, "%s\t%d\t%.2f%s\t%.2f%s\t%.0f\t%.0f\t%.2fx (%.0f%%)\t%.2fx (%.0f%%)\t%s\n",
+				name, threads,
+				r.Old.Mean, r.Unit, r.New.Mean, r.Unit,
+				1/(r.Old.Mean*unit), 1/(r.New.Mean*unit),
+				oSpeedup, oEff*100, nSpeedup, nEff*100,
+				note)
+		}
+	}
+
+	return p.w.Flush()
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+const trendUsageExtra = `
+Shows how every benchmark evolved over a series of results, oldest
+first: the estimator(-est) of the first and last results, the change
+between them and a sparkline of all the results. The steps are the
+results which are significantly different from the previous one(p <
+0.05) by at least -min-delta %, labeled with the file name or the
+revision of the store entry.
+
+With -cp, the change points are detected with a sliding window over the
+series: every result and the -window-1 results after it are compared
+with the -window results before it, pooling their repetitions, which
+finds smaller changes than the steps. Of consecutive significant
+changes, only the most significant one is reported, with the range of
+results to bisect, from the last result before the change to the first
+one after it.
+
+Without files, the series is made of the store entries(see "store -h")
+selected by -rev, -host, -tag, -since and -until, ordered by the dates
+of their results(see "store -h").
+`
+
+func trendUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s trend [options] [file...]\n", os.Args[0])
+		fmt.Fprint(os.Stderr, "options:\n")
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, trendUsageExtra)
+		os.Exit(1)
+	}
+}
+
+// estimators are the statistics of a sample which can be followed in
+// a trend.
+var estimators = map[string]func(s *Sample) float64{
+	"mean":   func(s *Sample) float64 { return s.Mean },
+	"median": func(s *Sample) float64 { return Percentile(s.RValues, 0.5) },
+	"min":    func(s *Sample) float64 { return s.Min },
+}
+
+// A Trend is the series of samples of a benchmark in a series of
+// results.
+type Trend struct {
+	Name string
+	Unit string
+	Key  BenchName
+	// Samples has a sample for every result, nil if the benchmark is
+	// missing from it or failed.
+	Samples []*Sample
+}
+
+// A Step is a significant change between a sample of a trend and the
+// previous one.
+type Step struct {
+	// Index is the index of the result with the changed sample.
+	Index int
+	Comparison
+}
+
+// GetTrends returns the trends of what, which is "real", "cpu" or the
+// name of a user counter, for all the benchmarks in the series of
+// metrics, in the order in which they are first seen.
+func GetTrends(what string, series [][]Metric) []Trend {
+	var trends []Trend
+	index := make(map[string]int)
+	for i, metrics := range series {
+		for k := range metrics {
+			m := &metrics[k]
+			if !m.Ok() {
+				continue
+			}
+
+			var s *Sample
+			unit := m.TimeUnit
+			switch what {
+			case "real":
+				s = &m.RealTime
+			case "cpu":
+				s = &m.CPUTime
+			default:
+				s = m.Counters[what]
+				unit = ""
+			}
+			if s == nil {
+				continue
+			}
+
+			j, ok := index[m.KeyString]
+			if !ok {
+				j = len(trends)
+				index[m.KeyString] = j
+				trends = append(trends, Trend{
+					Name:    m.Name,
+					Unit:    unit,
+					Key:     m.Key,
+					Samples: make([]*Sample, len(series)),
+				})
+			}
+			trends[j].Samples[i] = s
+		}
+	}
+	return trends
+}
+
+// Values returns the estimator of every sample of t, NaN for the
+// missing ones.
+func (t Trend) Values(est func(s *Sample) float64) []float64 {
+	values := make([]float64, len(t.Samples))
+	for i, s := range t.Samples {
+		values[i] = math.NaN()
+		if s != nil {
+			values[i] = est(s)
+		}
+	}
+	return values
+}
+
+// Steps compares every sample of t with the previous one and returns
+// the significant changes of at least minDelta %.
+func (t Trend) Steps(minDelta float64) []Step {
+	var steps []Step
+	var prev *Sample
+	for i, s := range t.Samples {
+		if s == nil {
+			continue
+		}
+		if prev != nil {
+			c := prev.Compare(*s)
+			if c.Significant && math.Abs(c.Delta) >= minDelta {
+				steps = append(steps, Step{Index: i, Comparison: c})
+			}
+		}
+		prev = s
+	}
+	return steps
+}
+
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values scaled between their minimum and maximum, with
+// a space for NaN.
+func sparkline(values []float64) string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo = math.Min(lo, v)
+			hi = math.Max(hi, v)
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			b.WriteRune(' ')
+		case hi == lo:
+			b.WriteRune(sparkLevels[0])
+		default:
+			i := int((v - lo) / (hi - lo) * float64(len(sparkLevels)-1))
+			b.WriteRune(sparkLevels[i])
+		}
+	}
+	return b.String()
+}
+
+// firstLast returns the first and last values which are not NaN.
+func firstLast(values []float64) (float64, float64) {
+	first, last := math.NaN(), math.NaN()
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		if math.IsNaN(first) {
+			first = v
+		}
+		last = v
+	}
+	return first, last
+}
+
+// PrintTrend prints the trends of what with the estimator est. labels
+// are the names of the results of the series.
+func (p Printer) PrintTrend(what, est string, labels []string, trends []Trend, minDelta float64) error {
+	title := what
+	if what == "real" || what == "cpu" {
+		title += " time"
+	}
+
+	fmt.Fprintf(p.w, "%s\tfirst\tlast\tchange\ttrend\tsteps\n", title)
+	fmt.Fprintf(p.w, "%s\t-----\t----\t------\t-----\t-----\n", strings.Repeat("-", len(title)))
+	for _, t := range trends {
+		values := t.Values(estimators[est])
+		first, last := firstLast(values)
+
+		var steps []string
+		for _, s := range t.Steps(minDelta) {
+			steps = append(steps, fmt.Sprintf("%s(%+.2f%%)", labels[s.Index], s.Delta))
+		}
+
+		fmt.Fprintf(p.w, "%s\t%.2f%s\t%.2f%s\t%+.2f%%\t%s\t%s\n",
+			t.Name, first, t.Unit, last, t.Unit, (last-first)/first*100,
+			sparkline(values), orDash(strings.Join(steps, " ")))
+	}
+	return p.w.Flush()
+}
+
+// entryLabel returns the label of a store entry in a trend.
+func entryLabel(e StoreEntry) string {
+	if len(e.Revision) > 8 {
+		return e.Revision[:8]
+	}
+	if e.Revision != "" {
+		return e.Revision
+	}
+	return fmt.Sprintf("#%d", e.ID)
+}
+
+// trendCmd implements the trend command.
+func trendCmd(args []string) error {
+	var fFormat string
+	var fStore string
+	var fMetric string
+	var fEst string
+	var fMinDelta float64
+	var fChangePoints bool
+	var fWindow int
+	var q StoreQuery
+	var fo filterOptions
+
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	fs.StringVar(&fFormat, "format", "auto", "input format, one of: "+inputFormatNames())
+	fs.StringVar(&fStore, "store", defaultStoreDir(), "the store directory, used when no files are given")
+	fs.StringVar(&fMetric, "metric", "real", "the metric to follow: real, cpu or the name of a user counter")
+	fs.StringVar(&fEst, "est", "mean", "the estimator to follow: mean, median or min")
+	fs.Float64Var(&fMinDelta, "min-delta", 0, "the smallest change in % reported as a step")
+	fs.BoolVar(&fChangePoints, "cp", false, "detect the change points with a sliding window")
+	fs.IntVar(&fWindow, "window", 3, "with -cp, the number of results on each side of a change point")
+	q.register(fs)
+	fo.register(fs)
+	fs.Usage = trendUsage(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, ok := estimators[fEst]; !ok {
+		return fmt.Errorf("unknown estimator '%s'", fEst)
+	}
+	if fWindow < 1 {
+		return errors.New("-window must be at least 1")
+	}
+	if err := fo.init(); err != nil {
+		return err
+	}
+
+	var labels []string
+	var series [][]Metric
+	if fs.NArg() > 0 {
+		for _, path := range fs.Args() {
+			res, err := loadResult(path, fFormat)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			labels = append(labels, filepath.Base(path))
+			series = append(series, GetMetrics(res.Benchmarks, fo.filter))
+		}
+	} else {
+		s, err := OpenStore(fStore)
+		if err != nil {
+			return err
+		}
+		for _, e := range s.Query(q) {
+			res, err := s.Load(e)
+			if err != nil {
+				return fmt.Errorf("entry %d: %w", e.ID, err)
+			}
+			labels = append(labels, entryLabel(e))
+			series = append(series, GetMetrics(res.Benchmarks, fo.filter))
+		}
+	}
+	if len(series) < 2 {
+		return errors.New("a trend needs at least two results")
+	}
+
+	printer := Printer{w: tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)}
+	trends := GetTrends(fMetric, series)
+	if err := printer.PrintTrend(fMetric, fEst, labels, trends, fMinDelta); err != nil {
+		return err
+	}
+
+	if fChangePoints {
+		var points []ChangePoint
+		for _, t := range trends {
+			points = append(points, t.ChangePoints(fWindow, fMinDelta)...)
+		}
+		fmt.Fprintln(printer.w)
+		return printer.PrintChangePoints(fMetric, labels, points)
+	}
+	return nil
+}
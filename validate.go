@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxIterationsRatio is the ratio between the old and new number of
+// iterations above which they are considered not comparable, e.g. when
+// the benchmarks ran with different --benchmark_min_time.
+const maxIterationsRatio = 10
+
+// A Diagnostic is a problem found with the repetitions or iterations of
+// a benchmark, which makes its comparison unreliable.
+type Diagnostic struct {
+	Name  string
+	Issue string
+	Old   string
+	New   string
+}
+
+// Validate checks that the old and new benchmarks ran with comparable
+// and sufficient numbers of repetitions and iterations.
+func Validate(old, new []Metric, minReps int, minIters uint64) []Diagnostic {
+	var diags []Diagnostic
+	index := metricIndex(new)
+	for _, o := range old {
+		i, ok := index[o.KeyString]
+		if !ok {
+			continue
+		}
+		n := new[i]
+
+		reps := func(m Metric) string { return fmt.Sprintf("%d reps", m.Repetitions()) }
+		iters := func(m Metric) string {
+			if m.MinIterations == m.MaxIterations {
+				return fmt.Sprintf("%d iters", m.MinIterations)
+			}
+			return fmt.Sprintf("%d-%d iters", m.MinIterations, m.MaxIterations)
+		}
+		add := func(issue string, old, new string) {
+			diags = append(diags, Diagnostic{Name: n.Name, Issue: issue, Old: old, New: new})
+		}
+
+		if o.Repetitions() != n.Repetitions() {
+			add("repetitions differ", reps(o), reps(n))
+		}
+		if o.Repetitions() < minReps || n.Repetitions() < minReps {
+			add(fmt.Sprintf("less than %d repetitions", minReps), reps(o), reps(n))
+		}
+
+		// the iterations are unknown(0) for some inputs, e.g. criterion
+		// estimates, and always 1 for single runs of a program
+		if !o.Ok() || !n.Ok() || o.MinIterations == 0 || n.MinIterations == 0 ||
+			o.SingleRun || n.SingleRun {
+			continue
+		}
+		if o.MinIterations < minIters || n.MinIterations < minIters {
+			add(fmt.Sprintf("less than %d iterations", minIters), iters(o), iters(n))
+		}
+		lo, hi := o.MinIterations, n.MinIterations
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if hi/lo >= maxIterationsRatio {
+			add("iterations differ", iters(o), iters(n))
+		}
+	}
+	return diags
+}
+
+// PrintDiagnostics prints the diagnostics returned by Validate.
+func (p Printer) PrintDiagnostics(diags []Diagnostic) error {
+	title := "diagnostics"
+	fmt.Fprintf(p.w, "%s\tissue\told\tnew\n", title)
+	fmt.Fprintf(p.w, "%s\t-----\t---\t---\n", strings.Repeat("-", len(title)))
+	for _, d := range diags {
+		fmt.Fprintf(p.w, "%s\t%s\t%s\t%s\n", d.Name, d.Issue, d.Old, d.New)
+	}
+	return p.w.Flush()
+}